@@ -4,7 +4,11 @@
 
 package graph
 
-import "github.com/r3labs/diff"
+import (
+	"sort"
+
+	"github.com/r3labs/diff"
+)
 
 func prefixChanges(prefix string, cl diff.Changelog) diff.Changelog {
 	for i := 0; i < len(cl); i++ {
@@ -13,3 +17,68 @@ func prefixChanges(prefix string, cl diff.Changelog) diff.Changelog {
 
 	return cl
 }
+
+// componentValues returns all values of a component as created or deleted entries in a changelog. The
+// entries of struct components also include the component's '_component_id', '_provider' and '_component',
+// so the component's type can be found and its deletion detected regardless of how its fields are tagged
+func componentValues(t string, c Component) (diff.Changelog, error) {
+	var cl diff.Changelog
+
+	gc, ok := c.(*GenericComponent)
+	if !ok {
+		values, err := diff.StructValues(t, []string{c.GetID()}, c)
+		if err != nil {
+			return nil, err
+		}
+
+		cl = componentChanges(t, c.GetID(), map[string]interface{}{
+			"_component_id": c.GetID(),
+			"_provider":     c.GetProvider(),
+			"_component":    c.GetType(),
+		})
+
+		for _, change := range values {
+			if len(change.Path) == 2 && isReserved(change.Path[1]) {
+				continue
+			}
+			cl = append(cl, change)
+		}
+
+		return cl, nil
+	}
+
+	values := make(map[string]interface{})
+	for k, v := range *gc {
+		if k != "_action" && k != "_state" {
+			values[k] = v
+		}
+	}
+
+	return componentChanges(t, c.GetID(), values), nil
+}
+
+// componentChanges returns created or deleted entries for a component's values, sorted by name
+func componentChanges(t, id string, values map[string]interface{}) diff.Changelog {
+	var cl diff.Changelog
+
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		change := diff.Change{Type: t, Path: []string{id, k}, To: values[k]}
+		if t == diff.DELETE {
+			change.From, change.To = change.To, nil
+		}
+		cl = append(cl, change)
+	}
+
+	return cl
+}
+
+func isReserved(name string) bool {
+	return name == "_component_id" || name == "_provider" || name == "_component"
+}
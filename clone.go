@@ -0,0 +1,163 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/r3labs/diff"
+)
+
+// Clone returns a deep copy of the graph. Components that implement Cloner are copied using their
+// Clone method, while all others are copied by serialising them to json and loading them again as
+// their registered type, falling back to the component's own type or a GenericComponent
+func (g *Graph) Clone() (*Graph, error) {
+	var err error
+
+	ng := &Graph{
+		ID:            g.ID,
+		Name:          g.Name,
+		UserID:        g.UserID,
+		Username:      g.Username,
+		Action:        g.Action,
+		PreventCycles: g.PreventCycles,
+	}
+
+	if g.Options != nil {
+		ng.Options = deepCopy(g.Options).(map[string]interface{})
+	}
+
+	ng.Components, err = cloneComponents(g.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	ng.Changes, err = cloneComponents(g.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.Edges != nil {
+		ng.Edges = make([]Edge, len(g.Edges))
+		copy(ng.Edges, g.Edges)
+	}
+
+	for _, change := range g.Changelog {
+		ng.Changelog = append(ng.Changelog, diff.Change{
+			Type: change.Type,
+			Path: append([]string{}, change.Path...),
+			From: deepCopy(change.From),
+			To:   deepCopy(change.To),
+		})
+	}
+
+	return ng, nil
+}
+
+// SafeDiff : diffs clones of both graphs, so neither graph is modified
+func (g *Graph) SafeDiff(og *Graph) (*Graph, error) {
+	return g.safeDiff(og, false)
+}
+
+// SafeDiffWithChangelog : diffs clones of both graphs and produces a changelog, so neither graph is modified
+func (g *Graph) SafeDiffWithChangelog(og *Graph) (*Graph, error) {
+	return g.safeDiff(og, true)
+}
+
+func (g *Graph) safeDiff(og *Graph, changelog bool) (*Graph, error) {
+	ng, err := g.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	nog, err := og.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return ng.diff(nog, changelog)
+}
+
+// CloneComponent returns a deep copy of a component
+func CloneComponent(c Component) (Component, error) {
+	switch v := c.(type) {
+	case Cloner:
+		return v.Clone(), nil
+	case *ReplacedComponent:
+		rc, err := CloneComponent(v.Component)
+		if err != nil {
+			return nil, err
+		}
+		return &ReplacedComponent{Component: rc}, nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nc := NewComponent(c.GetProvider(), c.GetType())
+
+	if nc == nil {
+		t := reflect.TypeOf(c)
+		if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+			nc, _ = reflect.New(t.Elem()).Interface().(Component)
+		}
+	}
+
+	if nc == nil {
+		gc := make(GenericComponent)
+		nc = &gc
+	}
+
+	return nc, json.Unmarshal(data, nc)
+}
+
+func cloneComponents(components []Component) ([]Component, error) {
+	if components == nil {
+		return nil, nil
+	}
+
+	cloned := make([]Component, len(components))
+
+	for i, c := range components {
+		nc, err := CloneComponent(c)
+		if err != nil {
+			return nil, err
+		}
+		cloned[i] = nc
+	}
+
+	return cloned, nil
+}
+
+// deepCopy copies all maps and slices of a value
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, i := range x {
+			m[k] = deepCopy(i)
+		}
+		return m
+	case map[string]string:
+		m := make(map[string]string, len(x))
+		for k, i := range x {
+			m[k] = i
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for k, i := range x {
+			s[k] = deepCopy(i)
+		}
+		return s
+	case []string:
+		return append([]string{}, x...)
+	}
+
+	return v
+}
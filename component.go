@@ -4,7 +4,11 @@
 
 package graph
 
-import "github.com/r3labs/diff"
+import (
+	"time"
+
+	"github.com/r3labs/diff"
+)
 
 // Component : representation of a component
 type Component interface {
@@ -20,7 +24,7 @@ type Component interface {
 	GetGroup() string                       // returns the components group name
 	GetTags() map[string]string             // returns the tags associated with the component
 	GetTag(string) string                   // returns the tag associated with the component
-	Diff(Component) (diff.Changelog, error) // should return changelog
+	Diff(Component) (diff.Changelog, error) // returns the changes from the previous version of the component passed in, to this component
 	Update(Component)                       // updates the values stored on the component
 	Rebuild(*Graph)                         // rebuilds the internal state of the component, a component set is passed in
 	Validate() error                        // validates the component's values
@@ -29,3 +33,23 @@ type Component interface {
 	IsStateful() bool                       // returns if the component is stateful. This is important to work out if a component can be skipped when deleting its dependencies (pruning).
 	SequentialDependencies() []string       // returns a list of origin components that restrict the execution of its dependents, allowing only one dependent component to be provisioned at a time (sequentially)
 }
+
+// Replaceable : optional interface for components that can not apply all changes in place
+type Replaceable interface {
+	RequiresReplacement(diff.Changelog) bool // returns true if the changes can only be applied by replacing the component
+}
+
+// CreateBeforeDestroyer : optional interface for replaceable components, to control the order of a replacement
+type CreateBeforeDestroyer interface {
+	CreateBeforeDestroy() bool // returns true if the new version of the component should be created before the previous version is deleted
+}
+
+// DurationEstimator : optional interface for components that can estimate how long they take to be processed
+type DurationEstimator interface {
+	EstimatedDuration() time.Duration // returns the estimated time it takes to process the component
+}
+
+// Cloner : optional interface for components that can create a deep copy of themselves
+type Cloner interface {
+	Clone() Component // returns a deep copy of the component
+}
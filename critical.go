@@ -0,0 +1,150 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "time"
+
+// Schedule describes the estimated duration of processing a graph
+type Schedule struct {
+	Path     []string                 // the ids of all components on the critical path, in order
+	Duration time.Duration            // the total estimated duration of the critical path
+	Slack    map[string]time.Duration // how long each component can be delayed without delaying the graph
+}
+
+// CriticalPath returns the longest weighted path through the graph. Each component takes the time returned by its
+// EstimatedDuration if it implements DurationEstimator, while other components and the start and end vertices take
+// no time. Only these per-component estimates are used, so the length of an edge does not affect the critical path.
+// Durations are read when the critical path is calculated. Components with no slack are on the critical path
+func (g *Graph) CriticalPath() (*Schedule, error) {
+	var order []string
+
+	vertices := make(map[string]bool)
+	indegree := make(map[string]int)
+	neighbours := make(map[string][]string)
+	origins := make(map[string][]string)
+
+	add := func(id string) {
+		if !vertices[id] {
+			vertices[id] = true
+			order = append(order, id)
+		}
+	}
+
+	for _, c := range g.vertices() {
+		add(c.GetID())
+	}
+
+	for _, e := range g.Edges {
+		add(e.Source)
+		add(e.Destination)
+		neighbours[e.Source] = append(neighbours[e.Source], e.Destination)
+		origins[e.Destination] = append(origins[e.Destination], e.Source)
+		indegree[e.Destination]++
+	}
+
+	var sorted []string
+	for _, id := range order {
+		if indegree[id] == 0 {
+			sorted = append(sorted, id)
+		}
+	}
+
+	for i := 0; i < len(sorted); i++ {
+		for _, n := range neighbours[sorted[i]] {
+			indegree[n]--
+			if indegree[n] == 0 {
+				sorted = append(sorted, n)
+			}
+		}
+	}
+
+	if len(sorted) < len(order) {
+		return nil, &CycleError{Cycle: g.firstCycle()}
+	}
+
+	ix := g.index()
+
+	durations := make(map[string]time.Duration)
+	for _, id := range sorted {
+		durations[id] = ix.duration(id)
+	}
+
+	// the earliest time each vertex can finish
+	finish := make(map[string]time.Duration)
+	previous := make(map[string]string)
+
+	var last string
+	var duration time.Duration
+
+	for _, id := range sorted {
+		var start time.Duration
+
+		for _, o := range origins[id] {
+			if _, ok := previous[id]; !ok || finish[o] > start {
+				start = finish[o]
+				previous[id] = o
+			}
+		}
+
+		finish[id] = start + durations[id]
+
+		if last == "" || finish[id] > duration {
+			last = id
+			duration = finish[id]
+		}
+	}
+
+	// the latest time each vertex can finish without delaying the graph
+	latest := make(map[string]time.Duration)
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		id := sorted[i]
+
+		latest[id] = duration
+		for _, n := range neighbours[id] {
+			if l := latest[n] - durations[n]; l < latest[id] {
+				latest[id] = l
+			}
+		}
+	}
+
+	s := Schedule{
+		Duration: duration,
+		Slack:    make(map[string]time.Duration),
+	}
+
+	for id := range vertices {
+		if !isTerminal(id) {
+			s.Slack[id] = latest[id] - finish[id]
+		}
+	}
+
+	for id, ok := last, last != ""; ok; id, ok = previous[id] {
+		if !isTerminal(id) {
+			s.Path = append([]string{id}, s.Path...)
+		}
+	}
+
+	return &s, nil
+}
+
+// duration returns the estimated duration of a vertex, or 0 if it can not be estimated
+func (ix *index) duration(id string) time.Duration {
+	if isTerminal(id) {
+		return 0
+	}
+
+	c := ix.componentAll(id)
+	if rc, ok := c.(*ReplacedComponent); ok {
+		c = rc.Component
+	}
+
+	e, ok := c.(DurationEstimator)
+	if !ok || e.EstimatedDuration() < 0 {
+		return 0
+	}
+
+	return e.EstimatedDuration()
+}
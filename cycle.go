@@ -0,0 +1,279 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// CycleError is returned when connecting two vertices would create a cycle
+type CycleError struct {
+	Cycle []string
+}
+
+// Error returns the cycle as a string, i.e. 'a -> b -> a'
+func (e *CycleError) Error() string {
+	if len(e.Cycle) < 1 {
+		return "Graph contains a cycle"
+	}
+	path := append([]string{}, e.Cycle...)
+	return "Graph contains a cycle: " + strings.Join(append(path, e.Cycle[0]), " -> ")
+}
+
+// HasCycle returns true if the graph's edges contain a cycle
+func (g *Graph) HasCycle() bool {
+	return g.firstCycle() != nil
+}
+
+// firstCycle returns the first cycle found by a depth first search of the graph's edges, or nil if there are none
+func (g *Graph) firstCycle() []string {
+	vertices, adj := g.adjacency()
+	return findCycle(vertices, adj)
+}
+
+// findCycle returns the path closed by the first back edge found by a depth first search
+func findCycle(vertices []string, adj map[string][]string) []string {
+	var path []string
+
+	// 0 = unvisited, 1 = in progress, 2 = done
+	visited := make(map[string]int)
+
+	var visit func(v string) []string
+	visit = func(v string) []string {
+		visited[v] = 1
+		path = append(path, v)
+
+		for _, n := range adj[v] {
+			switch visited[n] {
+			case 1:
+				for i := range path {
+					if path[i] == n {
+						return append([]string{}, path[i:]...)
+					}
+				}
+			case 0:
+				if cycle := visit(n); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		visited[v] = 2
+
+		return nil
+	}
+
+	for _, v := range vertices {
+		if visited[v] != 0 {
+			continue
+		}
+		if cycle := visit(v); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// cyclicComponents returns one cycle from every strongly connected component of the graph that contains
+// a cycle, in order of appearance. Unlike Cycles, the number of cycles returned is bounded by the number of
+// vertices, as each strongly connected component is found once using Tarjan's algorithm
+func (g *Graph) cyclicComponents() [][]string {
+	var cycles [][]string
+	var stack []string
+
+	vertices, adj := g.adjacency()
+
+	order := make(map[string]int)
+	for i, v := range vertices {
+		order[v] = i
+	}
+
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onstack := make(map[string]bool)
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onstack[v] = true
+
+		for _, n := range adj[v] {
+			if _, ok := index[n]; !ok {
+				connect(n)
+				if lowlink[n] < lowlink[v] {
+					lowlink[v] = lowlink[n]
+				}
+			} else if onstack[n] && index[n] < lowlink[v] {
+				lowlink[v] = index[n]
+			}
+		}
+
+		if lowlink[v] != index[v] {
+			return
+		}
+
+		var scc []string
+		members := make(map[string]bool)
+
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onstack[n] = false
+			members[n] = true
+			scc = append(scc, n)
+			if n == v {
+				break
+			}
+		}
+
+		sort.Slice(scc, func(i, j int) bool {
+			return order[scc[i]] < order[scc[j]]
+		})
+
+		// search for a cycle using only the edges between members of the strongly connected component
+		sadj := make(map[string][]string)
+
+		for _, m := range scc {
+			for _, n := range adj[m] {
+				if members[n] {
+					sadj[m] = append(sadj[m], n)
+				}
+			}
+		}
+
+		if cycle := findCycle(scc, sadj); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+
+	for _, v := range vertices {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+
+	sort.SliceStable(cycles, func(i, j int) bool {
+		return order[cycles[i][0]] < order[cycles[j][0]]
+	})
+
+	return cycles
+}
+
+// Cycles returns every elementary cycle in the graph as an ordered list of component id's.
+// Each cycle starts at the vertex that appears first in the graph, the edge from the last
+// vertex back to the first is implied. The start and end vertices are ignored. As the number of
+// elementary cycles can grow exponentially with the number of edges, use HasCycle to detect a cycle.
+func (g *Graph) Cycles() [][]string {
+	var cycles [][]string
+
+	vertices, adj := g.adjacency()
+
+	order := make(map[string]int)
+	for i, v := range vertices {
+		order[v] = i
+	}
+
+	for i, s := range vertices {
+		var path []string
+		onpath := make(map[string]bool)
+
+		// only search vertices ordered after s, so each cycle is reported once
+		var search func(v string)
+		search = func(v string) {
+			path = append(path, v)
+			onpath[v] = true
+
+			for _, n := range adj[v] {
+				if n == s {
+					cycle := make([]string, len(path))
+					copy(cycle, path)
+					cycles = append(cycles, cycle)
+					continue
+				}
+				if order[n] > i && !onpath[n] {
+					search(n)
+				}
+			}
+
+			onpath[v] = false
+			path = path[:len(path)-1]
+		}
+
+		search(s)
+	}
+
+	return cycles
+}
+
+// findPath returns a path of vertex id's between source and destination, if one exists
+func (ix *index) findPath(source, destination string) []string {
+	adj := ix.neighbours
+
+	visited := make(map[string]bool)
+
+	var search func(v string) []string
+	search = func(v string) []string {
+		if v == destination {
+			return []string{v}
+		}
+		visited[v] = true
+		for _, n := range adj[v] {
+			if visited[n] {
+				continue
+			}
+			if p := search(n); p != nil {
+				return append([]string{v}, p...)
+			}
+		}
+		return nil
+	}
+
+	return search(source)
+}
+
+// adjacency returns the graph's vertices in order of appearance and their neighbouring vertices,
+// excluding the start and end vertices
+func (g *Graph) adjacency() ([]string, map[string][]string) {
+	var vertices []string
+
+	adj := make(map[string][]string)
+	seen := make(map[string]bool)
+
+	add := func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			vertices = append(vertices, v)
+		}
+	}
+
+	for _, c := range g.Components {
+		add(c.GetID())
+	}
+
+	for _, c := range g.Changes {
+		add(c.GetID())
+	}
+
+	for _, e := range g.Edges {
+		if isTerminal(e.Source) || isTerminal(e.Destination) {
+			continue
+		}
+		add(e.Source)
+		add(e.Destination)
+		adj[e.Source] = append(adj[e.Source], e.Destination)
+	}
+
+	return vertices, adj
+}
+
+// isTerminal returns true if the vertex is the graph's start or end vertex
+func isTerminal(id string) bool {
+	return id == "start" || id == "end"
+}
@@ -0,0 +1,460 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// dotKeys maps DOT attributes to the reserved keys of a generic component
+var dotKeys = map[string]string{
+	"action":      "_action",
+	"type":        "_component",
+	"provider":    "_provider",
+	"provider_id": "_provider_id",
+	"state":       "_state",
+	"name":        "_name",
+	"group":       "_group",
+	"stateful":    "_stateful",
+}
+
+// dotIgnored holds attributes that only affect how a graph is rendered
+var dotIgnored = map[string]bool{
+	"label":     true,
+	"shape":     true,
+	"color":     true,
+	"style":     true,
+	"fillcolor": true,
+	"fontcolor": true,
+	"penwidth":  true,
+}
+
+// FromDOT parses a subset of the DOT language into a graph of generic components. Node statements,
+// edge chains, default node and edge attributes and subgraphs are supported, with subgraphs being
+// flattened into the graph. Node attributes such as 'action', 'type' and 'provider' are mapped onto
+// their reserved keys, while graph and edge attributes are ignored, so every edge has a length of 1.
+// The start and end vertices are not added as components
+func FromDOT(r io.Reader) (*Graph, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := dotTokenize(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	g := New()
+
+	p := dotParser{tokens: tokens, graph: g, index: g.index(), nodes: make(map[string]*GenericComponent)}
+
+	err = p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.graph, nil
+}
+
+type dotToken struct {
+	value  string
+	quoted bool
+	line   int
+}
+
+type dotScope struct {
+	node map[string]string
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	graph  *Graph
+	index  *index
+	nodes  map[string]*GenericComponent
+}
+
+func (p *dotParser) parse() error {
+	if p.keyword("strict") {
+		p.pos++
+	}
+
+	if !p.keyword("digraph") {
+		return p.unexpected()
+	}
+	p.pos++
+
+	if p.id() {
+		p.graph.Name = p.next().value
+	}
+
+	_, err := p.block(dotScope{node: map[string]string{}})
+	if err != nil {
+		return err
+	}
+
+	if p.pos < len(p.tokens) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+// block parses a list of statements enclosed in braces, returning all nodes declared within it
+func (p *dotParser) block(scope dotScope) ([]string, error) {
+	var declared []string
+
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.is("}") {
+		if p.pos >= len(p.tokens) {
+			return nil, errors.New("Unexpected end of DOT file")
+		}
+
+		if p.is(";") {
+			p.pos++
+			continue
+		}
+
+		switch {
+		case p.keyword("graph"), p.keyword("edge"):
+			p.pos++
+			_, err = p.attributes()
+		case p.keyword("node"):
+			p.pos++
+			err = p.defaults(scope.node)
+		case p.id() && p.peek(1) == "=":
+			p.pos += 3
+		default:
+			var ids []string
+			ids, err = p.statement(scope)
+			declared = append(declared, ids...)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.pos++
+
+	return declared, nil
+}
+
+// statement parses a node or edge statement
+func (p *dotParser) statement(scope dotScope) ([]string, error) {
+	var operands [][]string
+	var declared []string
+
+	for {
+		ids, err := p.operand(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, ids)
+		declared = append(declared, ids...)
+
+		if !p.is("->") {
+			break
+		}
+		p.pos++
+	}
+
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operands) == 1 {
+		for _, id := range operands[0] {
+			p.node(id, scope.node, attrs)
+		}
+		return declared, nil
+	}
+
+	for i := 1; i < len(operands); i++ {
+		for _, source := range operands[i-1] {
+			for _, destination := range operands[i] {
+				p.index.connect(source, destination)
+			}
+		}
+	}
+
+	return declared, nil
+}
+
+// operand parses a node id or a subgraph, returning the nodes it refers to
+func (p *dotParser) operand(scope dotScope) ([]string, error) {
+	if p.keyword("subgraph") || p.is("{") {
+		if p.keyword("subgraph") {
+			p.pos++
+			if p.id() {
+				p.pos++
+			}
+		}
+
+		return p.block(dotScope{node: mergeAttributes(scope.node, nil)})
+	}
+
+	if !p.id() {
+		return nil, p.unexpected()
+	}
+
+	id := p.next().value
+
+	// ports are not supported, so are skipped
+	for p.is(":") && p.peekID(1) {
+		p.pos += 2
+	}
+
+	p.node(id, scope.node, nil)
+
+	return []string{id}, nil
+}
+
+// node declares a node, updating its values if it already exists
+func (p *dotParser) node(id string, defaults, attrs map[string]string) {
+	if isTerminal(id) {
+		return
+	}
+
+	gc, ok := p.nodes[id]
+	if !ok {
+		gc = MapGenericComponent(map[string]interface{}{"_component_id": id})
+		p.nodes[id] = gc
+		p.index.addComponent(gc)
+
+		for k, v := range defaults {
+			gc.setDOTAttribute(k, v)
+		}
+	}
+
+	for k, v := range attrs {
+		gc.setDOTAttribute(k, v)
+	}
+}
+
+// defaults parses a list of default attributes into a scope
+func (p *dotParser) defaults(scope map[string]string) error {
+	attrs, err := p.attributes()
+	if err != nil {
+		return err
+	}
+
+	for k, v := range attrs {
+		scope[k] = v
+	}
+
+	return nil
+}
+
+// attributes parses any number of attribute lists
+func (p *dotParser) attributes() (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	for p.is("[") {
+		p.pos++
+
+		for !p.is("]") {
+			if p.is(",") || p.is(";") {
+				p.pos++
+				continue
+			}
+
+			if !p.id() || p.peek(1) != "=" || !p.peekID(2) {
+				return nil, p.unexpected()
+			}
+
+			attrs[p.tokens[p.pos].value] = p.tokens[p.pos+2].value
+			p.pos += 3
+		}
+
+		p.pos++
+	}
+
+	return attrs, nil
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *dotParser) peek(n int) string {
+	if p.pos+n >= len(p.tokens) || p.tokens[p.pos+n].quoted {
+		return ""
+	}
+	return p.tokens[p.pos+n].value
+}
+
+func (p *dotParser) peekID(n int) bool {
+	if p.pos+n >= len(p.tokens) {
+		return false
+	}
+	return dotID(p.tokens[p.pos+n])
+}
+
+func (p *dotParser) is(value string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].value == value
+}
+
+func (p *dotParser) id() bool {
+	return p.peekID(0) && !p.keyword("node") && !p.keyword("edge") && !p.keyword("graph") && !p.keyword("subgraph")
+}
+
+func (p *dotParser) keyword(keyword string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].value, keyword)
+}
+
+func (p *dotParser) expect(value string) error {
+	if !p.is(value) {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *dotParser) unexpected() error {
+	if p.pos >= len(p.tokens) {
+		return errors.New("Unexpected end of DOT file")
+	}
+
+	t := p.tokens[p.pos]
+
+	return errors.New("Unexpected token in DOT file on line " + strconv.Itoa(t.line) + ": " + t.value)
+}
+
+// setDOTAttribute sets a value of a generic component from a DOT attribute
+func (gc *GenericComponent) setDOTAttribute(key, value string) {
+	if dotIgnored[key] {
+		return
+	}
+
+	rk, ok := dotKeys[key]
+	if !ok {
+		(*gc)[key] = value
+		return
+	}
+
+	if rk == "_stateful" {
+		stateful, err := strconv.ParseBool(value)
+		if err == nil {
+			(*gc)[rk] = stateful
+		}
+		return
+	}
+
+	(*gc)[rk] = value
+}
+
+// dotID returns true if a token can be used as an ID
+func dotID(t dotToken) bool {
+	if t.quoted {
+		return true
+	}
+
+	for _, r := range t.value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' {
+			return false
+		}
+	}
+
+	return t.value != "->" && t.value != "--"
+}
+
+// dotTokenize splits a DOT file into tokens, removing all comments
+func dotTokenize(s string) ([]dotToken, error) {
+	var tokens []dotToken
+
+	rs := []rune(s)
+	line := 1
+
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+
+		switch {
+		case r == '\n':
+			line++
+		case unicode.IsSpace(r):
+		case r == '#' || (r == '/' && i+1 < len(rs) && rs[i+1] == '/'):
+			for i+1 < len(rs) && rs[i+1] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			i += 2
+			for i+1 < len(rs) && !(rs[i] == '*' && rs[i+1] == '/') {
+				if rs[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(rs) {
+				return nil, errors.New("Unterminated comment in DOT file on line " + strconv.Itoa(line))
+			}
+			i++
+		case r == '"':
+			var value []rune
+			start := line
+			for i++; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+					i++
+				}
+				if rs[i] == '\n' {
+					line++
+				}
+				value = append(value, rs[i])
+			}
+			if i >= len(rs) {
+				return nil, errors.New("Unterminated string in DOT file on line " + strconv.Itoa(start))
+			}
+			tokens = append(tokens, dotToken{value: string(value), quoted: true, line: start})
+		case r == '-' && i+1 < len(rs) && (rs[i+1] == '>' || rs[i+1] == '-'):
+			if rs[i+1] == '-' {
+				return nil, errors.New("Undirected edges are not supported in DOT file on line " + strconv.Itoa(line))
+			}
+			tokens = append(tokens, dotToken{value: "->", line: line})
+			i++
+		case strings.ContainsRune("{}[]=;,:", r):
+			tokens = append(tokens, dotToken{value: string(r), line: line})
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.' || (rs[j] == '-' && j == i)) {
+				j++
+			}
+			tokens = append(tokens, dotToken{value: string(rs[i:j]), line: line})
+			i = j - 1
+		default:
+			return nil, errors.New("Unexpected character in DOT file on line " + strconv.Itoa(line) + ": " + string(r))
+		}
+	}
+
+	return tokens, nil
+}
+
+// mergeAttributes returns a copy of a map, overwritten with the values of another
+func mergeAttributes(a, b map[string]string) map[string]string {
+	m := make(map[string]string)
+
+	for k, v := range a {
+		m[k] = v
+	}
+
+	for k, v := range b {
+		m[k] = v
+	}
+
+	return m
+}
@@ -0,0 +1,60 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError holds a collection of errors
+type MultiError struct {
+	Errors []error
+}
+
+// Error returns all errors as a string
+func (e *MultiError) Error() string {
+	var msgs []string
+
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d error(s) occurred: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// DependencyError is returned when a component depends on a component that does not exist
+type DependencyError struct {
+	Component  string
+	Dependency string
+}
+
+// Error returns the missing dependency as a string
+func (e *DependencyError) Error() string {
+	return "Component " + e.Component + " depends on a component that does not exist: " + e.Dependency
+}
+
+// ComponentError is returned when a component fails validation
+type ComponentError struct {
+	ComponentID string
+	Err         error
+}
+
+// Error returns the component's id and error as a string
+func (e *ComponentError) Error() string {
+	return "Component " + e.ComponentID + ": " + e.Err.Error()
+}
+
+// ConflictError is returned when a change's previous value does not match the value it is applied to
+type ConflictError struct {
+	Path  []string
+	From  interface{}
+	Value interface{}
+}
+
+// Error returns the conflicting path as a string
+func (e *ConflictError) Error() string {
+	return "Value has changed since the changelog was created: " + strings.Join(e.Path, ".")
+}
@@ -0,0 +1,339 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r3labs/graph"
+)
+
+// ANY : matches any provider, type or action when registering a handler
+const ANY = "*"
+
+// Handler processes a single component
+type Handler func(ctx context.Context, c graph.Component) error
+
+// Error holds the errors returned by all failed components, keyed by component id
+type Error struct {
+	Errors map[string]error
+}
+
+// Error returns all component errors as a string
+func (e *Error) Error() string {
+	var ids []string
+	var msgs []string
+
+	for id := range e.Errors {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		msgs = append(msgs, id+": "+e.Errors[id].Error())
+	}
+
+	return fmt.Sprintf("%d component(s) failed: %s", len(ids), strings.Join(msgs, ", "))
+}
+
+// Executor processes the changes of a diffed graph, walking its edges from start to end
+type Executor struct {
+	Workers int
+	// Locker, if set, is held while the executor reads the graph and sets the state of its
+	// components, so the graph can be read concurrently, i.e. by setting it to a graph.SafeGraph
+	Locker sync.Locker
+	// Journal, if set, records every state transition, so a run can be resumed if the process dies
+	Journal  *graph.Journal
+	graph    *graph.Graph
+	handlers map[string]Handler
+}
+
+type result struct {
+	component graph.Component
+	err       error
+}
+
+type plan struct {
+	changes    []graph.Component
+	position   map[string]int
+	pending    map[string]int
+	dependents map[string][]string
+	slack      map[string]time.Duration
+	ready      []graph.Component
+}
+
+// New returns a new executor for a graph
+func New(g *graph.Graph) *Executor {
+	return &Executor{
+		Workers:  runtime.NumCPU(),
+		graph:    g,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers a handler for components matching a provider, type and action. ANY can be used as a wildcard
+func (e *Executor) Handle(provider, ctype, action string, h Handler) {
+	e.handlers[key(provider, ctype, action)] = h
+}
+
+// Run processes all changes. A change is processed as soon as all of its origins have completed.
+// If a change fails, all of its dependents are skipped, while independent changes are still processed.
+// Changes that are already completed are not processed again. When more changes are ready than there
+// are workers, changes on the critical path are processed first. If a state transition can not be
+// recorded to the Journal, no further changes are processed and the error is returned.
+func (e *Executor) Run(ctx context.Context) error {
+	workers := e.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	e.lock()
+	p, err := e.plan()
+	e.unlock()
+
+	if err != nil {
+		return err
+	}
+
+	ready := p.ready
+
+	jobs := make(chan graph.Component, len(p.changes))
+	results := make(chan result, len(p.changes))
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for c := range jobs {
+				results <- result{component: c, err: e.process(ctx, c)}
+			}
+		}()
+	}
+
+	defer close(jobs)
+
+	var jerr error
+
+	failed := make(map[string]error)
+	running := 0
+
+	record := func(err error) {
+		if jerr == nil {
+			jerr = err
+		}
+	}
+
+	for {
+		for len(ready) > 0 && running < workers && ctx.Err() == nil && jerr == nil {
+			i := critical(ready, p.slack)
+			c := ready[i]
+			ready = append(ready[:i], ready[i+1:]...)
+
+			// components are only dispatched once they are recorded as running
+			state := c.GetState()
+
+			err := e.setState(c, graph.STATERUNNING)
+			if err != nil {
+				e.lock()
+				c.SetState(state)
+				e.unlock()
+
+				record(err)
+				break
+			}
+
+			jobs <- c
+			running++
+		}
+
+		if running < 1 {
+			break
+		}
+
+		r := <-results
+		running--
+
+		id := r.component.GetID()
+
+		if r.err != nil {
+			record(e.setState(r.component, graph.STATEERRORED))
+			record(e.skip(p, id))
+			failed[id] = r.err
+			continue
+		}
+
+		record(e.setState(r.component, graph.STATECOMPLETED))
+
+		for _, d := range p.dependents[id] {
+			p.pending[d]--
+			dc := p.changes[p.position[d]]
+			if p.pending[d] == 0 && dc.GetState() != graph.STATESKIPPED {
+				ready = append(ready, dc)
+			}
+		}
+	}
+
+	if jerr != nil {
+		return jerr
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if len(failed) > 0 {
+		return &Error{Errors: failed}
+	}
+
+	return nil
+}
+
+// plan reads the graph's changes and edges, returning the changes that are ready to be processed
+func (e *Executor) plan() (*plan, error) {
+	_, err := e.graph.Waves()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := e.graph.CriticalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	p := plan{
+		changes:    e.graph.Changes,
+		position:   make(map[string]int),
+		pending:    make(map[string]int),
+		dependents: make(map[string][]string),
+		slack:      schedule.Slack,
+	}
+
+	for i, c := range p.changes {
+		p.position[c.GetID()] = i
+	}
+
+	seen := make(map[graph.Edge]bool)
+
+	for _, edge := range e.graph.Edges {
+		_, hasSource := p.position[edge.Source]
+		_, hasDestination := p.position[edge.Destination]
+
+		k := graph.Edge{Source: edge.Source, Destination: edge.Destination}
+		if !hasSource || !hasDestination || seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		p.dependents[edge.Source] = append(p.dependents[edge.Source], edge.Destination)
+		if p.changes[p.position[edge.Source]].GetState() != graph.STATECOMPLETED {
+			p.pending[edge.Destination]++
+		}
+	}
+
+	for _, c := range p.changes {
+		if p.pending[c.GetID()] == 0 && c.GetState() != graph.STATECOMPLETED {
+			p.ready = append(p.ready, c)
+		}
+	}
+
+	return &p, nil
+}
+
+// setState sets the state of a component, holding the executor's Locker if set
+// and recording the transition to the executor's Journal if set
+func (e *Executor) setState(c graph.Component, state string) error {
+	e.lock()
+	defer e.unlock()
+
+	if e.Journal != nil {
+		return e.Journal.SetState(c, state)
+	}
+
+	c.SetState(state)
+
+	return nil
+}
+
+func (e *Executor) lock() {
+	if e.Locker != nil {
+		e.Locker.Lock()
+	}
+}
+
+func (e *Executor) unlock() {
+	if e.Locker != nil {
+		e.Locker.Unlock()
+	}
+}
+
+func (e *Executor) process(ctx context.Context, c graph.Component) error {
+	h := e.handler(c)
+	if h == nil {
+		return errors.New("No handler found for component: " + c.GetID())
+	}
+
+	return h(ctx, c)
+}
+
+// handler returns the most specific handler registered for a component
+func (e *Executor) handler(c graph.Component) Handler {
+	for _, p := range []string{c.GetProvider(), ANY} {
+		for _, t := range []string{c.GetType(), ANY} {
+			for _, a := range []string{c.GetAction(), ANY} {
+				if h, ok := e.handlers[key(p, t, a)]; ok {
+					return h
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// critical returns the position of the ready component with the least slack, so components
+// on the critical path are processed first
+func critical(ready []graph.Component, slack map[string]time.Duration) int {
+	n := 0
+
+	for i, c := range ready {
+		if slack[c.GetID()] < slack[ready[n].GetID()] {
+			n = i
+		}
+	}
+
+	return n
+}
+
+// skip marks all dependents of a component as skipped
+func (e *Executor) skip(p *plan, id string) error {
+	for _, d := range p.dependents[id] {
+		dc := p.changes[p.position[d]]
+		if dc.GetState() == graph.STATESKIPPED || dc.GetState() == graph.STATECOMPLETED {
+			continue
+		}
+
+		err := e.setState(dc, graph.STATESKIPPED)
+		if err != nil {
+			return err
+		}
+
+		err = e.skip(p, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func key(provider, ctype, action string) string {
+	return provider + "/" + ctype + "/" + action
+}
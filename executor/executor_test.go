@@ -0,0 +1,258 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r3labs/graph"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testComponent(id, action string) graph.Component {
+	c := make(graph.GenericComponent)
+	c["_component_id"] = id
+	c["_component"] = "instance"
+	c["_provider"] = "test"
+	c["_action"] = action
+	c["_state"] = graph.STATEWAITING
+	return &c
+}
+
+type estimatedComponent struct {
+	*graph.GenericComponent
+	duration time.Duration
+}
+
+func (c *estimatedComponent) EstimatedDuration() time.Duration {
+	return c.duration
+}
+
+// testGraph builds the graph 1 -> (2, 3) -> 4, with an independent component 5
+func testGraph() *graph.Graph {
+	g := graph.New()
+	g.Changes = []graph.Component{
+		testComponent("1", graph.ACTIONCREATE),
+		testComponent("2", graph.ACTIONCREATE),
+		testComponent("3", graph.ACTIONUPDATE),
+		testComponent("4", graph.ACTIONCREATE),
+		testComponent("5", graph.ACTIONDELETE),
+	}
+	g.Edges = []graph.Edge{
+		{Source: "start", Destination: "1"},
+		{Source: "start", Destination: "5"},
+		{Source: "1", Destination: "2"},
+		{Source: "1", Destination: "3"},
+		{Source: "2", Destination: "4"},
+		{Source: "3", Destination: "4"},
+		{Source: "4", Destination: "end"},
+		{Source: "5", Destination: "end"},
+	}
+	return g
+}
+
+func TestExecutor(t *testing.T) {
+	Convey("Given a diffed graph", t, func() {
+		g := testGraph()
+		e := New(g)
+
+		var mu sync.Mutex
+		var order []string
+
+		record := func(ctx context.Context, c graph.Component) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, c.GetID())
+			return nil
+		}
+
+		position := func(id string) int {
+			for i, v := range order {
+				if v == id {
+					return i
+				}
+			}
+			return -1
+		}
+
+		Convey("When running it with handlers for all components", func() {
+			e.Handle("test", "instance", ANY, record)
+			err := e.Run(context.Background())
+			Convey("It should process all components after their origins", func() {
+				So(err, ShouldBeNil)
+				So(len(order), ShouldEqual, 5)
+				So(position("1"), ShouldBeLessThan, position("2"))
+				So(position("1"), ShouldBeLessThan, position("3"))
+				So(position("2"), ShouldBeLessThan, position("4"))
+				So(position("3"), ShouldBeLessThan, position("4"))
+				for _, c := range g.Changes {
+					So(c.GetState(), ShouldEqual, graph.STATECOMPLETED)
+				}
+			})
+		})
+
+		Convey("When running it with a single worker", func() {
+			e.Workers = 1
+			e.Handle(ANY, ANY, ANY, record)
+			err := e.Run(context.Background())
+			Convey("It should process components in order", func() {
+				So(err, ShouldBeNil)
+				So(order, ShouldResemble, []string{"1", "5", "2", "3", "4"})
+			})
+		})
+
+		Convey("When running it with a single worker and estimated durations", func() {
+			g.Changes[4] = &estimatedComponent{GenericComponent: g.Changes[4].(*graph.GenericComponent), duration: 10 * time.Second}
+			e.Workers = 1
+			e.Handle(ANY, ANY, ANY, record)
+			err := e.Run(context.Background())
+			Convey("It should process components on the critical path first", func() {
+				So(err, ShouldBeNil)
+				So(order, ShouldResemble, []string{"5", "1", "2", "3", "4"})
+			})
+		})
+
+		Convey("When running it while the graph is read concurrently", func() {
+			sg := graph.NewSafeGraph(g)
+			e.Locker = sg
+			e.Handle(ANY, ANY, ANY, record)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					sg.ToJSON()
+					sg.GetChanges()
+				}
+			}()
+
+			err := e.Run(context.Background())
+			<-done
+
+			Convey("It should process all components", func() {
+				So(err, ShouldBeNil)
+				So(len(order), ShouldEqual, 5)
+				for _, c := range sg.GetChanges() {
+					So(c.GetState(), ShouldEqual, graph.STATECOMPLETED)
+				}
+			})
+		})
+
+		Convey("When running it with a journal", func() {
+			dir, err := ioutil.TempDir("", "journal")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "journal")
+
+			j, err := graph.CreateJournal(path, g)
+			So(err, ShouldBeNil)
+
+			e.Journal = j
+			e.Handle(ANY, ANY, graph.ACTIONCREATE, record)
+			e.Handle(ANY, ANY, graph.ACTIONUPDATE, func(ctx context.Context, c graph.Component) error {
+				return errors.New("update failed")
+			})
+			e.Handle(ANY, ANY, graph.ACTIONDELETE, record)
+
+			So(e.Run(context.Background()), ShouldNotBeNil)
+			So(j.Close(), ShouldBeNil)
+
+			Convey("It should record every state transition", func() {
+				f, err := os.Open(path)
+				So(err, ShouldBeNil)
+				defer f.Close()
+
+				rg := graph.New()
+				plan, running, err := rg.Resume(f)
+				So(err, ShouldBeNil)
+				So(running, ShouldBeEmpty)
+
+				for _, c := range g.Changes {
+					So(rg.ComponentAll(c.GetID()).GetState(), ShouldEqual, c.GetState())
+				}
+
+				So(len(plan.Changes), ShouldEqual, 2)
+				So(plan.Changes[0].GetState(), ShouldEqual, graph.STATEERRORED)
+				So(plan.Changes[1].GetState(), ShouldEqual, graph.STATESKIPPED)
+			})
+		})
+
+		Convey("When running it with a journal that can not be written", func() {
+			dir, err := ioutil.TempDir("", "journal")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			j, err := graph.CreateJournal(filepath.Join(dir, "journal"), g)
+			So(err, ShouldBeNil)
+			So(j.Close(), ShouldBeNil)
+
+			e.Journal = j
+			e.Handle(ANY, ANY, ANY, record)
+
+			err = e.Run(context.Background())
+
+			Convey("It should not process any components", func() {
+				So(err, ShouldNotBeNil)
+				So(len(order), ShouldEqual, 0)
+				for _, c := range g.Changes {
+					So(c.GetState(), ShouldEqual, graph.STATEWAITING)
+				}
+			})
+		})
+
+		Convey("When a component fails", func() {
+			e.Handle("test", "instance", ANY, record)
+			e.Handle("test", "instance", graph.ACTIONUPDATE, func(ctx context.Context, c graph.Component) error {
+				return errors.New("update failed")
+			})
+			err := e.Run(context.Background())
+			Convey("It should skip its dependents and finish independent components", func() {
+				So(err, ShouldNotBeNil)
+				So(err.(*Error).Errors["3"].Error(), ShouldEqual, "update failed")
+				So(g.Changes[0].GetState(), ShouldEqual, graph.STATECOMPLETED)
+				So(g.Changes[1].GetState(), ShouldEqual, graph.STATECOMPLETED)
+				So(g.Changes[2].GetState(), ShouldEqual, graph.STATEERRORED)
+				So(g.Changes[3].GetState(), ShouldEqual, graph.STATESKIPPED)
+				So(g.Changes[4].GetState(), ShouldEqual, graph.STATECOMPLETED)
+				So(position("4"), ShouldEqual, -1)
+			})
+		})
+
+		Convey("When a component has no handler", func() {
+			e.Handle("test", "instance", graph.ACTIONCREATE, record)
+			err := e.Run(context.Background())
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.(*Error).Errors["3"], ShouldNotBeNil)
+				So(err.(*Error).Errors["5"], ShouldNotBeNil)
+				So(g.Changes[3].GetState(), ShouldEqual, graph.STATESKIPPED)
+			})
+		})
+
+		Convey("When the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			e.Workers = 1
+			e.Handle(ANY, ANY, ANY, func(ctx context.Context, c graph.Component) error {
+				cancel()
+				return record(ctx, c)
+			})
+			err := e.Run(ctx)
+			Convey("It should stop processing components", func() {
+				So(err, ShouldEqual, context.Canceled)
+				So(order, ShouldResemble, []string{"1"})
+				So(g.Changes[0].GetState(), ShouldEqual, graph.STATECOMPLETED)
+				So(g.Changes[1].GetState(), ShouldEqual, graph.STATEWAITING)
+			})
+		})
+	})
+}
@@ -4,39 +4,62 @@
 
 package graph
 
-import "github.com/r3labs/diff"
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff"
+)
+
+// reservedKeys hold the graph's metadata of a generic component. They are ignored when diffing components
+var reservedKeys = map[string]bool{
+	"_component_id":            true,
+	"_component":               true,
+	"_provider":                true,
+	"_provider_id":             true,
+	"_name":                    true,
+	"_group":                   true,
+	"_tags":                    true,
+	"_dependencies":            true,
+	"_sequential_dependencies": true,
+	"_stateful":                true,
+	"_action":                  true,
+	"_state":                   true,
+}
 
 // GenericComponent is a representation of a component backed by a map[string]interface{}
 type GenericComponent map[string]interface{}
 
 // GetID : returns the component's ID
 func (gc *GenericComponent) GetID() string {
-	return (*gc)["_component_id"].(string)
+	return gc.str("_component_id")
 }
 
 // GetName returns a components name
 func (gc *GenericComponent) GetName() string {
-	return ""
+	return gc.str("_name")
 }
 
 // GetProvider : returns the provider type
 func (gc *GenericComponent) GetProvider() string {
-	return (*gc)["_provider"].(string)
+	return gc.str("_provider")
 }
 
 // GetProviderID returns a components provider id
 func (gc *GenericComponent) GetProviderID() string {
-	return ""
+	return gc.str("_provider_id")
 }
 
 // GetType : returns the type of the component
 func (gc *GenericComponent) GetType() string {
-	return (*gc)["_component"].(string)
+	return gc.str("_component")
 }
 
 // GetState : returns the state of the component
 func (gc *GenericComponent) GetState() string {
-	return (*gc)["_state"].(string)
+	return gc.str("_state")
 }
 
 // SetState : sets the state of the component
@@ -46,7 +69,7 @@ func (gc *GenericComponent) SetState(state string) {
 
 // GetAction : returns the action of the component
 func (gc *GenericComponent) GetAction() string {
-	return (*gc)["_action"].(string)
+	return gc.str("_action")
 }
 
 // SetAction : Sets the action of the component
@@ -56,27 +79,42 @@ func (gc *GenericComponent) SetAction(action string) {
 
 // GetGroup : returns the components group
 func (gc *GenericComponent) GetGroup() string {
-	return ""
+	return gc.str("_group")
 }
 
 // GetTags returns a components tags
 func (gc *GenericComponent) GetTags() map[string]string {
-	return map[string]string{}
+	tags := make(map[string]string)
+
+	switch t := (*gc)["_tags"].(type) {
+	case map[string]string:
+		for k, v := range t {
+			tags[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range t {
+			if s, ok := v.(string); ok {
+				tags[k] = s
+			}
+		}
+	}
+
+	return tags
 }
 
 // GetTag returns a components tag
-func (gc *GenericComponent) GetTag(string) string {
-	return ""
+func (gc *GenericComponent) GetTag(tag string) string {
+	return gc.GetTags()[tag]
 }
 
 // Dependencies : returns a list of component id's upon which the component depends
 func (gc *GenericComponent) Dependencies() []string {
-	return []string{}
+	return gc.strs("_dependencies")
 }
 
 // SequentialDependencies : returns a list of origin components that restrict the execution of its dependents, allowing only one dependent component to be provisioned at a time (sequentially)
 func (gc *GenericComponent) SequentialDependencies() []string {
-	return []string{}
+	return gc.strs("_sequential_dependencies")
 }
 
 // Validate : validates the components values
@@ -84,9 +122,49 @@ func (gc *GenericComponent) Validate() error {
 	return nil
 }
 
-// Diff : diff's the component against another component of the same type
+// Diff : diff's the component against a previous version of the component. Reserved keys are ignored
 func (gc *GenericComponent) Diff(v Component) (diff.Changelog, error) {
-	return diff.Changelog{}, nil
+	var cl diff.Changelog
+
+	ov, ok := v.(*GenericComponent)
+	if !ok {
+		return nil, diff.ErrTypeMismatch
+	}
+
+	a, err := normalise(ov.values())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := normalise(gc.values())
+	if err != nil {
+		return nil, err
+	}
+
+	// values that have changed type cannot be diffed, so are treated as a single update
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok || av == nil || bv == nil || reflect.TypeOf(av) == reflect.TypeOf(bv) {
+			continue
+		}
+
+		cl = append(cl, diff.Change{Type: diff.UPDATE, Path: []string{k}, From: av, To: bv})
+		delete(a, k)
+		delete(b, k)
+	}
+
+	changes, err := diff.Diff(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	cl = append(cl, changes...)
+
+	sort.SliceStable(cl, func(i, j int) bool {
+		return strings.Join(cl[i].Path, ".") < strings.Join(cl[j].Path, ".")
+	})
+
+	return cl, nil
 }
 
 // SetDefaultVariables : sets up the default template variables for a component
@@ -96,13 +174,35 @@ func (gc *GenericComponent) SetDefaultVariables() {}
 func (gc *GenericComponent) Rebuild(g *Graph) {}
 
 // Update : updates the provider returned values of a component
-func (gc *GenericComponent) Update(v Component) {}
+func (gc *GenericComponent) Update(v Component) {
+	ov, ok := v.(*GenericComponent)
+	if !ok {
+		return
+	}
+
+	for k, val := range *ov {
+		if k == "_action" || k == "_state" {
+			continue
+		}
+		(*gc)[k] = val
+	}
+}
 
 // IsStateful : returns true if the component needs to be actioned to be removed.
 func (gc *GenericComponent) IsStateful() bool {
-	return true
+	stateful, ok := (*gc)["_stateful"].(bool)
+	if !ok {
+		return true
+	}
+	return stateful
+}
+
+// Clone : returns a deep copy of the component
+func (gc *GenericComponent) Clone() Component {
+	return MapGenericComponent(deepCopy(map[string]interface{}(*gc)).(map[string]interface{}))
 }
 
+// MapGenericComponent creates a generic component from a map
 func MapGenericComponent(m map[string]interface{}) *GenericComponent {
 	c := make(GenericComponent)
 
@@ -112,3 +212,52 @@ func MapGenericComponent(m map[string]interface{}) *GenericComponent {
 
 	return &c
 }
+
+// values returns all non reserved values of the component
+func (gc *GenericComponent) values() map[string]interface{} {
+	values := make(map[string]interface{})
+
+	for k, v := range *gc {
+		if !reservedKeys[k] {
+			values[k] = v
+		}
+	}
+
+	return values
+}
+
+func (gc *GenericComponent) str(key string) string {
+	s, _ := (*gc)[key].(string)
+	return s
+}
+
+func (gc *GenericComponent) strs(key string) []string {
+	var s []string
+
+	switch v := (*gc)[key].(type) {
+	case []string:
+		s = append(s, v...)
+	case []interface{}:
+		for _, i := range v {
+			if is, ok := i.(string); ok {
+				s = append(s, is)
+			}
+		}
+	}
+
+	return s
+}
+
+// normalise converts all values to their json equivalent, so values of different numeric types can be compared
+func normalise(m map[string]interface{}) (map[string]interface{}, error) {
+	var nm map[string]interface{}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &nm)
+
+	return nm, err
+}
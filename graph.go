@@ -27,6 +27,21 @@ const (
 	ACTIONGET = "get"
 	// ACTIONNONE : action none, component wont be processed
 	ACTIONNONE = "none"
+	// ACTIONREPLACE : action replace, component will be created to replace its previous version
+	ACTIONREPLACE = "replace"
+)
+
+const (
+	// STATEWAITING : state waiting, component has not been processed yet
+	STATEWAITING = "waiting"
+	// STATERUNNING : state running, component is being processed
+	STATERUNNING = "running"
+	// STATECOMPLETED : state completed, component was processed successfully
+	STATECOMPLETED = "completed"
+	// STATEERRORED : state errored, component failed to be processed
+	STATEERRORED = "errored"
+	// STATESKIPPED : state skipped, component was not processed as one of its origins failed
+	STATESKIPPED = "skipped"
 )
 
 // Graph ...
@@ -41,6 +56,8 @@ type Graph struct {
 	Changes    []Component            `json:"changes,omitempty" diff:"-"`
 	Edges      []Edge                 `json:"edges,omitempty" diff:"-"`
 	Changelog  diff.Changelog         `json:"changelog,omitempty" diff:"-"`
+	// PreventCycles stops Connect from adding edges that would create a cycle
+	PreventCycles bool `json:"-" diff:"-"`
 }
 
 // New returns a new graph
@@ -51,6 +68,19 @@ func New() *Graph {
 	}
 }
 
+// emptyCopy returns a new graph with the same id, name, user, action and options, but no components or edges
+func (g *Graph) emptyCopy() *Graph {
+	ng := New()
+	ng.ID = g.ID
+	ng.Name = g.Name
+	ng.UserID = g.UserID
+	ng.Username = g.Username
+	ng.Action = g.Action
+	ng.Options = g.Options
+
+	return ng
+}
+
 // Component returns a component given the name matches
 func (g *Graph) Component(component string) Component {
 	for i, v := range g.Components {
@@ -123,6 +153,7 @@ func (g *Graph) DisconnectComponent(name string) error {
 		// Remove any edges that connect to the disconnected component
 		if g.Edges[i].Destination == name {
 			g.Edges = append(g.Edges[:i], g.Edges[i+1:]...)
+			continue
 		}
 
 		// Remove any neighbouring connections and reconnect them to origins
@@ -153,6 +184,12 @@ func (g *Graph) Connect(source, destination string) error {
 		return errors.New("Could not connect Component, does not exist")
 	}
 
+	if g.PreventCycles {
+		if path := g.index().findPath(destination, source); path != nil {
+			return &CycleError{Cycle: append([]string{source}, path[:len(path)-1]...)}
+		}
+	}
+
 	g.connect(source, destination)
 
 	return nil
@@ -169,23 +206,31 @@ func (g *Graph) ConnectMutually(source, destination string) error {
 
 // ConnectComplex adds a dependency between two vertices. If the source has more than 1 neighbouring vertex, the destination vertex will be connected to that.
 func (g *Graph) ConnectComplex(source, destination string) error {
-	if !g.HasComponent(source) {
+	return g.index().connectComplex(source, destination)
+}
+
+// ConnectComplexUpdate adds a dependency between two vertices. If the source has more than 1 neighbouring vertex, the destination vertex will be connected to that.
+func (g *Graph) ConnectComplexUpdate(source, destination string) error {
+	return g.index().connectComplexUpdate(source, destination, nil)
+}
+
+func (ix *index) connectComplex(source, destination string) error {
+	if ix.component(source) == nil {
 		source = "start"
 	}
 
-	if !g.HasComponent(destination) {
+	c := ix.component(destination)
+	if c == nil {
 		return errors.New("Could not connect Component, does not exist")
 	}
 
-	c := g.Component(destination)
-
 	if len(c.SequentialDependencies()) < 1 {
-		g.connect(source, destination)
+		ix.connect(source, destination)
 		return nil
 	}
 
 	for _, sdep := range c.SequentialDependencies() {
-		gc := g.Neighbours(source).GetSequentialDependency(sdep)
+		gc := ix.neighbourComponents(source).GetSequentialDependency(sdep)
 
 		// ensure that source does not get sent to itself (destination)
 		for gc != nil {
@@ -193,38 +238,76 @@ func (g *Graph) ConnectComplex(source, destination string) error {
 				break
 			}
 			source = gc.GetID()
-			gc = g.Neighbours(source).GetSequentialDependency(sdep)
+			gc = ix.neighbourComponents(source).GetSequentialDependency(sdep)
 		}
 
-		g.connect(source, destination)
+		ix.connect(source, destination)
 	}
 
 	return nil
 }
 
-// ConnectComplexUpdate adds a dependency between two vertices. If the source has more than 1 neighbouring vertex, the destination vertex will be connected to that.
-func (g *Graph) ConnectComplexUpdate(source, destination string) error {
-	if !g.HasComponent(source) {
+// connectComplexUpdate connects the destination to the end of the chain of components in its group that starts
+// at the source. If tails is set, the end of every chain walked is remembered, so later walks reaching a vertex
+// on the chain continue from its previous end. This is only valid while edges are added, but not removed
+func (ix *index) connectComplexUpdate(source, destination string, tails map[string]string) error {
+	if ix.component(source) == nil {
 		source = "start"
 	}
 
-	if !g.HasComponent(destination) {
+	c := ix.component(destination)
+	if c == nil {
 		return errors.New("Could not connect Component, does not exist")
 	}
 
-	c := g.Component(destination)
-	gc := g.Neighbours(source).GetComponentGroup(c.GetGroup())
+	group := c.GetGroup()
+
+	// the walk can only stop before the end of a chain if the destination is already part of it
+	skip := tails != nil && ix.nextInGroup(destination, group) == nil
+
+	var walked []string
+
+	gc := ix.nextInGroup(source, group)
 
 	// ensure that source does not get sent to itself (destination)
 	for gc != nil {
 		if destination == gc.GetID() {
 			break
 		}
+		walked = append(walked, source)
 		source = gc.GetID()
-		gc = g.Neighbours(source).GetComponentGroup(c.GetGroup())
+
+		if t, ok := tails[group+"/"+source]; ok && skip && t != source {
+			walked = append(walked, source)
+			source = t
+		}
+
+		gc = ix.nextInGroup(source, group)
 	}
 
-	g.connect(source, destination)
+	if tails != nil {
+		for _, id := range walked {
+			tails[group+"/"+id] = source
+		}
+	}
+
+	ix.connect(source, destination)
+
+	return nil
+}
+
+// nextInGroup returns the first neighbouring component of a vertex that belongs to a group
+func (ix *index) nextInGroup(id, group string) Component {
+	if group == "" {
+		return nil
+	}
+
+	for _, n := range ix.neighbours[id] {
+		c := ix.component(n)
+		if c != nil && c.GetGroup() == group {
+			return c
+		}
+	}
 
 	return nil
 }
@@ -302,8 +385,12 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 	// new temporary graph
 	ng := New()
 
+	ix := g.index()
+	oix := og.index()
+	nix := ng.index()
+
 	for _, c := range g.Components {
-		oc := og.Component(c.GetID())
+		oc := oix.component(c.GetID())
 		if oc != nil {
 			changes, err := c.Diff(oc)
 			if err != nil {
@@ -313,10 +400,22 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 			if len(changes) > 0 {
 				if c.GetAction() != ACTIONNONE {
 					c.SetAction(ACTIONUPDATE)
+
+					if requiresReplacement(c, changes) {
+						c.SetAction(ACTIONREPLACE)
+					}
 				}
 
-				c.SetState("waiting")
-				ng.AddComponent(c)
+				c.SetState(STATEWAITING)
+				nix.addComponent(c)
+
+				// the previous version is deleted as a separate step
+				if c.GetAction() == ACTIONREPLACE {
+					rc := &ReplacedComponent{Component: oc}
+					rc.SetAction(ACTIONDELETE)
+					rc.SetState(STATEWAITING)
+					nix.addComponent(rc)
+				}
 
 				if changelog {
 					changes = prefixChanges(c.GetID(), changes)
@@ -328,7 +427,7 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 				c.SetAction(ACTIONCREATE)
 
 				if changelog {
-					changes, err := diff.StructValues(diff.CREATE, []string{c.GetID()}, c)
+					changes, err := componentValues(diff.CREATE, c)
 					if err != nil {
 						return nil, err
 					}
@@ -336,8 +435,8 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 				}
 			}
 
-			c.SetState("waiting")
-			ng.AddComponent(c)
+			c.SetState(STATEWAITING)
+			nix.addComponent(c)
 		}
 	}
 
@@ -346,17 +445,17 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 			continue
 		}
 
-		c := g.Component(oc.GetID())
+		c := ix.component(oc.GetID())
 		if c == nil {
 			if oc.GetAction() != ACTIONNONE {
 				oc.SetAction(ACTIONDELETE)
 			}
 
-			oc.SetState("waiting")
-			ng.AddComponent(oc)
+			oc.SetState(STATEWAITING)
+			nix.addComponent(oc)
 
 			if changelog {
-				changes, err := diff.StructValues(diff.DELETE, []string{oc.GetID()}, oc)
+				changes, err := componentValues(diff.DELETE, oc)
 				if err != nil {
 					return nil, err
 				}
@@ -386,14 +485,16 @@ func (g *Graph) diff(og *Graph, changelog bool) (*Graph, error) {
 func (g *Graph) Graphviz() string {
 	var output []string
 
+	ix := g.index()
+
 	output = append(output, "digraph G {")
 
 	for _, edge := range g.Edges {
-		dest := g.ComponentAll(edge.Destination)
+		dest := ix.componentAll(edge.Destination)
 		if dest != nil {
-			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\"]", edge.Source, edge.Destination, dest.GetAction()))
+			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\"]", dotEscape(edge.Source), dotEscape(edge.Destination), dest.GetAction()))
 		} else {
-			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\"", edge.Source, edge.Destination))
+			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\"", dotEscape(edge.Source), dotEscape(edge.Destination)))
 		}
 	}
 
@@ -408,36 +509,83 @@ func (g *Graph) SetDiffDependencies() {
 
 	g.Edges = make([]Edge, 0)
 
+	ix := g.index()
+
+	// edges are only added, so the ends of the chains of updated components can be remembered
+	tails := make(map[string]string)
+
 	for _, c := range g.Components {
 		for _, dep := range c.Dependencies() {
 			switch c.GetAction() {
 			case ACTIONDELETE:
 				if c.IsStateful() {
-					g.ConnectComplex(c.GetID(), dep)
+					ix.connectComplex(c.GetID(), dep)
 				}
 			case ACTIONUPDATE:
-				g.ConnectComplexUpdate(dep, c.GetID())
-			case ACTIONCREATE, ACTIONFIND:
-				g.ConnectComplex(dep, c.GetID())
+				ix.connectComplexUpdate(dep, c.GetID(), tails)
+			case ACTIONCREATE, ACTIONFIND, ACTIONREPLACE:
+				ix.connectComplex(dep, c.GetID())
 			}
 		}
 	}
 
-	g.SetStartFinish()
+	ix.connectReplacements()
+
+	ix.setStartFinish()
+}
+
+// BuildDependencyEdges connects every component to the components it depends on. Dependencies
+// on components that do not exist are returned as a MultiError of DependencyError's
+func (g *Graph) BuildDependencyEdges(startFinish bool) error {
+	var errs []error
+
+	ix := g.index()
+
+	for _, c := range g.Components {
+		for _, dep := range c.Dependencies() {
+			if ix.component(dep) == nil {
+				errs = append(errs, &DependencyError{Component: c.GetID(), Dependency: dep})
+				continue
+			}
+
+			if g.PreventCycles {
+				if path := ix.findPath(c.GetID(), dep); path != nil {
+					errs = append(errs, &CycleError{Cycle: append([]string{dep}, path[:len(path)-1]...)})
+					continue
+				}
+			}
+
+			ix.connect(dep, c.GetID())
+		}
+	}
+
+	if startFinish {
+		ix.setStartFinish()
+	}
+
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+
+	return nil
 }
 
 // SetStartFinish sets a start and finish point
 func (g *Graph) SetStartFinish() {
-	for _, c := range g.Components {
-		o := g.Origins(c.GetID())
-		n := g.Neighbours(c.GetID())
+	g.index().setStartFinish()
+}
+
+func (ix *index) setStartFinish() {
+	for _, c := range ix.graph.Components {
+		o := ix.originComponents(c.GetID())
+		n := ix.neighbourComponents(c.GetID())
 
 		if len(*o) < 1 {
-			g.connect("start", c.GetID())
+			ix.connect("start", c.GetID())
 		}
 
 		if len(*n) < 1 {
-			g.connect(c.GetID(), "end")
+			ix.connect(c.GetID(), "end")
 		}
 	}
 }
@@ -447,13 +595,55 @@ func (g *Graph) ToJSON() ([]byte, error) {
 	return json.Marshal(g)
 }
 
-// Load loads a graph from map
+// UnmarshalJSON deserialises a graph from json. Components are created using their registered type, see Register
+func (g *Graph) UnmarshalJSON(data []byte) error {
+	type plain Graph
+
+	gg := struct {
+		*plain
+		Components []json.RawMessage `json:"components"`
+		Changes    []json.RawMessage `json:"changes,omitempty"`
+	}{plain: (*plain)(g)}
+
+	err := json.Unmarshal(data, &gg)
+	if err != nil {
+		return err
+	}
+
+	g.Components = make([]Component, len(gg.Components))
+	for i := 0; i < len(gg.Components); i++ {
+		g.Components[i], err = unmarshalComponent(gg.Components[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	g.Changes = nil
+	if gg.Changes != nil {
+		g.Changes = make([]Component, len(gg.Changes))
+	}
+	for i := 0; i < len(gg.Changes); i++ {
+		g.Changes[i], err = unmarshalComponent(gg.Changes[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Load loads a graph from map. Components are created using their registered type, see Register
 func (g *Graph) Load(gg map[string]interface{}) error {
+	var err error
+
 	components, ok := gg["components"].([]interface{})
 	if ok {
 		for i := 0; i < len(components); i++ {
 			c := components[i].(map[string]interface{})
-			components[i] = MapGenericComponent(c)
+			components[i], err = LoadComponent(c)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -461,11 +651,27 @@ func (g *Graph) Load(gg map[string]interface{}) error {
 	if ok {
 		for i := 0; i < len(changes); i++ {
 			c := changes[i].(map[string]interface{})
-			changes[i] = MapGenericComponent(c)
+			changes[i], err = LoadComponent(c)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	return mapstructure.Decode(gg, g)
+	err = mapstructure.Decode(gg, g)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// vertices returns the graph's changes, or its components if the graph has not been diffed
+func (g *Graph) vertices() []Component {
+	if len(g.Changes) > 0 {
+		return g.Changes
+	}
+	return g.Components
 }
 
 func (g *Graph) transferUnactionable() []Component {
@@ -489,7 +695,3 @@ func (g *Graph) bulkReplace(components []Component) {
 		g.AddComponent(c)
 	}
 }
-
-// func (g *Graph) DepthFirstSearch()
-
-// func (g *Graph) CycleDetection()
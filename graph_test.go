@@ -5,7 +5,17 @@
 package graph
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/r3labs/diff"
 	. "github.com/smartystreets/goconvey/convey"
@@ -77,7 +87,7 @@ func (tv *testComponent) Validate() error {
 }
 
 func (tv *testComponent) Diff(v Component) (diff.Changelog, error) {
-	return diff.Diff(tv, v)
+	return diff.Diff(v, tv)
 }
 
 func (tv *testComponent) SequentialDependencies() []string {
@@ -94,6 +104,72 @@ func (tv *testComponent) IsStateful() bool {
 	return true
 }
 
+type replaceableComponent struct {
+	testComponent
+	CBD       bool `json:"cbd" diff:"-"`
+	Stateless bool `json:"stateless" diff:"-"`
+}
+
+func (rc *replaceableComponent) IsStateful() bool {
+	return !rc.Stateless
+}
+
+func (rc *replaceableComponent) Diff(v Component) (diff.Changelog, error) {
+	return diff.Diff(v.(*replaceableComponent).testComponent, rc.testComponent)
+}
+
+func (rc *replaceableComponent) RequiresReplacement(cl diff.Changelog) bool {
+	return rc.Name == "1"
+}
+
+func (rc *replaceableComponent) CreateBeforeDestroy() bool {
+	return rc.CBD
+}
+
+type estimatedComponent struct {
+	testComponent
+	Duration time.Duration `json:"duration" diff:"-"`
+}
+
+func (ec *estimatedComponent) EstimatedDuration() time.Duration {
+	return ec.Duration
+}
+
+type invalidComponent struct {
+	testComponent
+}
+
+func (ic *invalidComponent) Validate() error {
+	return errors.New("Invalid value")
+}
+
+type registeredComponent struct {
+	testComponent
+	ID       string `json:"_component_id" diff:"-"`
+	Provider string `json:"_provider" diff:"-"`
+	Type     string `json:"_component" diff:"-"`
+}
+
+func (rc *registeredComponent) GetProvider() string {
+	return rc.Provider
+}
+
+func (rc *registeredComponent) GetType() string {
+	return rc.Type
+}
+
+type registeredReplaceableComponent struct {
+	registeredComponent
+}
+
+func (rc *registeredReplaceableComponent) Diff(v Component) (diff.Changelog, error) {
+	return diff.Diff(v.(*registeredReplaceableComponent).testComponent, rc.testComponent)
+}
+
+func (rc *registeredReplaceableComponent) RequiresReplacement(cl diff.Changelog) bool {
+	return true
+}
+
 func TestGraph(t *testing.T) {
 	Convey("Given a new graph", t, func() {
 		g := New()
@@ -208,6 +284,179 @@ func TestGraph(t *testing.T) {
 			})
 		})
 
+		Convey("When disconnecting a component that is the destination of the last edge", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			_ = g.Connect("test1", "test2")
+			err := g.DisconnectComponent("test2")
+			Convey("It should remove the edge", func() {
+				So(err, ShouldBeNil)
+				So(len(g.Edges), ShouldEqual, 0)
+				So(g.Connected("test1", "test2"), ShouldBeFalse)
+				So(len(*g.Neighbours("test1")), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When deleting a component", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			g.DeleteComponent(&testComponent{Name: "test1"})
+			Convey("It should no longer be found", func() {
+				So(len(g.Components), ShouldEqual, 1)
+				So(g.HasComponent("test1"), ShouldBeFalse)
+				So(g.Component("test1"), ShouldBeNil)
+				So(g.Component("test2"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When updating a component", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.UpdateComponent(&testComponent{Name: "test1", TestVal: 2})
+			Convey("It should return the updated component", func() {
+				So(g.Component("test1").(*testComponent).TestVal, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When replacing the components and edges of the graph", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			_ = g.Connect("test1", "test2")
+			g.Components = []Component{&testComponent{Name: "test3"}, &testComponent{Name: "test4"}}
+			g.Edges = []Edge{{Source: "test3", Destination: "test4", Length: 1}}
+			Convey("It should use the new components and edges", func() {
+				So(g.HasComponent("test1"), ShouldBeFalse)
+				So(g.HasComponent("test3"), ShouldBeTrue)
+				So(g.Connected("test1", "test2"), ShouldBeFalse)
+				So(g.Connected("test3", "test4"), ShouldBeTrue)
+				So((*g.Neighbours("test3"))[0].GetID(), ShouldEqual, "test4")
+				So((*g.Origins("test4"))[0].GetID(), ShouldEqual, "test3")
+			})
+		})
+
+		Convey("When building the dependency edges of the graph", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2", Deps: []string{"test1"}})
+			g.AddComponent(&testComponent{Name: "test3", Deps: []string{"test1", "test2"}})
+			err := g.BuildDependencyEdges(true)
+			Convey("It should connect every dependency", func() {
+				So(err, ShouldBeNil)
+				So(len(g.Edges), ShouldEqual, 5)
+				So(g.Connected("test1", "test2"), ShouldBeTrue)
+				So(g.Connected("test1", "test3"), ShouldBeTrue)
+				So(g.Connected("test2", "test3"), ShouldBeTrue)
+				So(g.Connected("start", "test1"), ShouldBeTrue)
+				So(g.Connected("test3", "end"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When building the dependency edges of a graph with missing dependencies", func() {
+			g.AddComponent(&testComponent{Name: "test1", Deps: []string{"fake1"}})
+			g.AddComponent(&testComponent{Name: "test2", Deps: []string{"test1", "fake2"}})
+			err := g.BuildDependencyEdges(false)
+			Convey("It should report every missing dependency", func() {
+				So(err, ShouldNotBeNil)
+				errs := err.(*MultiError).Errors
+				So(len(errs), ShouldEqual, 2)
+				So(errs[0].(*DependencyError).Component, ShouldEqual, "test1")
+				So(errs[0].(*DependencyError).Dependency, ShouldEqual, "fake1")
+				So(errs[1].(*DependencyError).Dependency, ShouldEqual, "fake2")
+				So(len(g.Edges), ShouldEqual, 1)
+				So(g.Connected("test1", "test2"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When assigning to the components and edges of the graph directly", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			_ = g.Connect("test1", "test2")
+			g.Components[0] = &testComponent{Name: "test3"}
+			g.Edges[0] = Edge{Source: "test3", Destination: "test2", Length: 1}
+			Convey("It should use the new components and edges", func() {
+				So(g.HasComponent("test1"), ShouldBeFalse)
+				So(g.Component("test3"), ShouldNotBeNil)
+				So(g.Connected("test1", "test2"), ShouldBeFalse)
+				So(g.Connected("test3", "test2"), ShouldBeTrue)
+				So((*g.Origins("test2"))[0].GetID(), ShouldEqual, "test3")
+			})
+		})
+
+		Convey("When appending to a slice of the graph's components", func() {
+			components := make([]Component, 0, 4)
+			g.Components = append(components, &testComponent{Name: "a"}, &testComponent{Name: "b"})
+			g.Components = append(g.Components[:1], &testComponent{Name: "c"})
+			Convey("It should use the appended components", func() {
+				So(g.HasComponent("b"), ShouldBeFalse)
+				So(g.HasComponent("c"), ShouldBeTrue)
+				So(g.Component("c"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When reading the graph from multiple goroutines", func() {
+			g.AddComponent(&testComponent{Name: "a"})
+			g.AddComponent(&testComponent{Name: "b"})
+			_ = g.Connect("a", "b")
+
+			var wg sync.WaitGroup
+
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					g.HasComponent("a")
+					g.Neighbours("a")
+					g.Descendants("a")
+					_, _ = g.Waves()
+				}()
+			}
+
+			wg.Wait()
+
+			Convey("It should not modify the graph", func() {
+				So(len(g.Components), ShouldEqual, 2)
+				So(len(g.Edges), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When setting the dependencies of updated components in the same group", func() {
+			build := func() *Graph {
+				ng := New()
+				for i := 0; i < 200; i++ {
+					var deps []string
+					for _, d := range []int{i / 2, i * 7 % (i + 1), i - 1} {
+						if d >= 0 && d < i {
+							deps = append(deps, strconv.Itoa(d))
+						}
+					}
+					action := ACTIONUPDATE
+					if i%5 == 0 {
+						action = ACTIONCREATE
+					}
+					ng.AddComponent(&testComponent{Name: strconv.Itoa(i), Deps: deps, Action: action})
+				}
+				return ng
+			}
+
+			g = build()
+			g.SetDiffDependencies()
+
+			// connect every update by walking its chain from the start
+			eg := build()
+			for _, c := range eg.Components {
+				for _, dep := range c.Dependencies() {
+					if c.GetAction() == ACTIONUPDATE {
+						eg.ConnectComplexUpdate(dep, c.GetID())
+					} else {
+						eg.ConnectComplex(dep, c.GetID())
+					}
+				}
+			}
+			eg.SetStartFinish()
+
+			Convey("It should chain the updates as if every chain was walked", func() {
+				So(g.Edges, ShouldResemble, eg.Edges)
+			})
+		})
+
 		Convey("When getting a component by name", func() {
 			g.AddComponent(&testComponent{Name: "test1"})
 			g.AddComponent(&testComponent{Name: "test2"})
@@ -232,6 +481,121 @@ func TestGraph(t *testing.T) {
 				So(exists, ShouldBeFalse)
 			})
 		})
+
+		Convey("When the graph's edges contain cycles", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			g.AddComponent(&testComponent{Name: "test3"})
+			g.AddComponent(&testComponent{Name: "test4"})
+			_ = g.Connect("test1", "test2")
+			_ = g.Connect("test2", "test3")
+			_ = g.Connect("test3", "test1")
+			_ = g.ConnectMutually("test3", "test4")
+			g.SetStartFinish()
+			Convey("It should detect every cycle", func() {
+				So(g.HasCycle(), ShouldBeTrue)
+				cycles := g.Cycles()
+				So(len(cycles), ShouldEqual, 2)
+				So(cycles[0], ShouldResemble, []string{"test1", "test2", "test3"})
+				So(cycles[1], ShouldResemble, []string{"test3", "test4"})
+			})
+		})
+
+		Convey("When the graph's edges contain a dense cycle", func() {
+			for i := 0; i < 12; i++ {
+				g.AddComponent(&testComponent{Name: "test" + strconv.Itoa(i)})
+			}
+			for i := 0; i < 12; i++ {
+				for j := 0; j < 12; j++ {
+					if i != j {
+						g.connect("test"+strconv.Itoa(i), "test"+strconv.Itoa(j))
+					}
+				}
+			}
+			g.SetStartFinish()
+			g.Changes = g.Components
+			Convey("It should report a single cycle without enumerating every cycle", func() {
+				So(g.HasCycle(), ShouldBeTrue)
+
+				_, err := g.Waves()
+				So(err, ShouldHaveSameTypeAs, &CycleError{})
+				So(err.(*CycleError).Cycle, ShouldResemble, []string{"test0", "test1"})
+
+				_, err = g.CriticalPath()
+				So(err, ShouldHaveSameTypeAs, &CycleError{})
+
+				var cycles int
+				for _, e := range g.Validate().(*MultiError).Errors {
+					if _, ok := e.(*ComponentError).Err.(*CycleError); ok {
+						cycles++
+					}
+				}
+				So(cycles, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the graph's edges contain cycles in separate components", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			g.AddComponent(&testComponent{Name: "test3"})
+			g.AddComponent(&testComponent{Name: "test4"})
+			g.connect("test1", "test2")
+			g.connect("test2", "test1")
+			g.connect("test2", "test3")
+			g.connect("test3", "test4")
+			g.connect("test4", "test3")
+			Convey("It should validate one cycle per strongly connected component", func() {
+				errs := g.Validate().(*MultiError).Errors
+				So(len(errs), ShouldEqual, 2)
+				So(errs[0].Error(), ShouldEqual, "Component test1: Graph contains a cycle: test1 -> test2 -> test1")
+				So(errs[1].Error(), ShouldEqual, "Component test3: Graph contains a cycle: test3 -> test4 -> test3")
+			})
+		})
+
+		Convey("When the graph's edges contain no cycles", func() {
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			g.AddComponent(&testComponent{Name: "test3"})
+			_ = g.Connect("test1", "test2")
+			_ = g.Connect("test1", "test3")
+			_ = g.Connect("test2", "test3")
+			g.SetStartFinish()
+			Convey("It should not detect a cycle", func() {
+				So(g.HasCycle(), ShouldBeFalse)
+				So(len(g.Cycles()), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When connecting verticies that would create a cycle and cycles are prevented", func() {
+			g.PreventCycles = true
+			g.AddComponent(&testComponent{Name: "test1"})
+			g.AddComponent(&testComponent{Name: "test2"})
+			g.AddComponent(&testComponent{Name: "test3"})
+			erra := g.Connect("test1", "test2")
+			errb := g.Connect("test2", "test3")
+			errc := g.Connect("test3", "test1")
+			Convey("It should refuse the edge and report the cycle", func() {
+				So(erra, ShouldBeNil)
+				So(errb, ShouldBeNil)
+				So(errc, ShouldNotBeNil)
+				ce, ok := errc.(*CycleError)
+				So(ok, ShouldBeTrue)
+				So(ce.Cycle, ShouldResemble, []string{"test3", "test1", "test2"})
+				So(errc.Error(), ShouldEqual, "Graph contains a cycle: test3 -> test1 -> test2 -> test3")
+				So(len(g.Edges), ShouldEqual, 2)
+				So(g.HasCycle(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When ordering changes that contain a cycle", func() {
+			g.Changes = []Component{&testComponent{Name: "test1"}, &testComponent{Name: "test2"}}
+			g.Edges = []Edge{{Source: "test1", Destination: "test2"}, {Source: "test2", Destination: "test1"}}
+			_, err := g.TopologicalSort()
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.(*CycleError).Cycle, ShouldResemble, []string{"test1", "test2"})
+			})
+		})
 	})
 
 	Convey("Given an existing graph", t, func() {
@@ -274,6 +638,68 @@ func TestGraph(t *testing.T) {
 					So(g.Edges[5].Source, ShouldEqual, "4")
 					So(g.Edges[5].Destination, ShouldEqual, "end")
 				})
+				Convey("It should return the correct order", func() {
+					sorted, err := g.TopologicalSort()
+					So(err, ShouldBeNil)
+					So(len(sorted), ShouldEqual, 4)
+					So(sorted[0].GetID(), ShouldEqual, "1")
+					So(sorted[1].GetID(), ShouldEqual, "2")
+					So(sorted[2].GetID(), ShouldEqual, "3")
+					So(sorted[3].GetID(), ShouldEqual, "4")
+				})
+				Convey("It should return the correct waves", func() {
+					waves, err := g.Waves()
+					So(err, ShouldBeNil)
+					So(len(waves), ShouldEqual, 3)
+					So(len(waves[0]), ShouldEqual, 1)
+					So(waves[0][0].GetID(), ShouldEqual, "1")
+					So(len(waves[1]), ShouldEqual, 2)
+					So(waves[1][0].GetID(), ShouldEqual, "2")
+					So(waves[1][1].GetID(), ShouldEqual, "3")
+					So(len(waves[2]), ShouldEqual, 1)
+					So(waves[2][0].GetID(), ShouldEqual, "4")
+				})
+			})
+		})
+
+		Convey("That has no verticies and was partially applied", func() {
+			eg := New()
+			g, _ := ng.Diff(eg)
+			g.Changes[0].SetState(STATECOMPLETED)
+			g.Changes[1].SetState(STATECOMPLETED)
+			g.Changes[2].SetState(STATECOMPLETED)
+			g.Changes[3].SetState(STATEERRORED)
+			Convey("When creating a rollback plan", func() {
+				rg, err := g.RollbackPlan()
+				Convey("It should delete the created components", func() {
+					So(err, ShouldBeNil)
+					So(len(rg.Changes), ShouldEqual, 3)
+					So(rg.Changes[0].GetID(), ShouldEqual, "1")
+					So(rg.Changes[0].GetAction(), ShouldEqual, ACTIONDELETE)
+					So(rg.Changes[0].GetState(), ShouldEqual, STATEWAITING)
+					So(rg.Changes[1].GetID(), ShouldEqual, "2")
+					So(rg.Changes[1].GetAction(), ShouldEqual, ACTIONDELETE)
+					So(rg.Changes[2].GetID(), ShouldEqual, "3")
+					So(rg.Changes[2].GetAction(), ShouldEqual, ACTIONDELETE)
+					So(g.Changes[0].GetAction(), ShouldEqual, ACTIONCREATE)
+				})
+				Convey("It should not share values with the diffed graph", func() {
+					rg.Changes[1].(*testComponent).Deps[0] = "changed"
+					So(g.Changes[1].(*testComponent).Deps[0], ShouldEqual, "1")
+				})
+				Convey("It should return the edges in reverse order", func() {
+					So(len(rg.Edges), ShouldEqual, 5)
+					So(rg.Edges[0].Source, ShouldEqual, "2")
+					So(rg.Edges[0].Destination, ShouldEqual, "1")
+					So(rg.Edges[1].Source, ShouldEqual, "3")
+					So(rg.Edges[1].Destination, ShouldEqual, "1")
+					So(rg.Edges[2].Source, ShouldEqual, "1")
+					So(rg.Edges[2].Destination, ShouldEqual, "end")
+					So(rg.Edges[3].Source, ShouldEqual, "start")
+					So(rg.Edges[3].Destination, ShouldEqual, "2")
+					So(rg.Edges[4].Source, ShouldEqual, "start")
+					So(rg.Edges[4].Destination, ShouldEqual, "3")
+				})
 			})
 		})
 
@@ -316,6 +742,15 @@ func TestGraph(t *testing.T) {
 					So(g.Edges[5].Source, ShouldEqual, "4")
 					So(g.Edges[5].Destination, ShouldEqual, "end")
 				})
+				Convey("It should return sequential waves", func() {
+					waves, err := g.Waves()
+					So(err, ShouldBeNil)
+					So(len(waves), ShouldEqual, 4)
+					So(waves[0][0].GetID(), ShouldEqual, "1")
+					So(waves[1][0].GetID(), ShouldEqual, "2")
+					So(waves[2][0].GetID(), ShouldEqual, "3")
+					So(waves[3][0].GetID(), ShouldEqual, "4")
+				})
 			})
 		})
 
@@ -367,6 +802,32 @@ func TestGraph(t *testing.T) {
 			})
 		})
 
+		Convey("That has an updated vertex '2' and additional vertex '5' that were applied", func() {
+			eg := New()
+			_ = eg.AddComponent(&testComponent{Name: "1", TestVal: 1})
+			_ = eg.AddComponent(&testComponent{Name: "2", Deps: []string{"1"}, TestVal: 2})
+			_ = eg.AddComponent(&testComponent{Name: "3", Deps: []string{"1"}, TestVal: 1})
+			_ = eg.AddComponent(&testComponent{Name: "4", Deps: []string{"2", "3"}, TestVal: 1})
+			_ = eg.AddComponent(&testComponent{Name: "5", Deps: []string{"1"}, TestVal: 1})
+			g, _ := ng.Diff(eg)
+			for _, c := range g.Changes {
+				c.SetState(STATECOMPLETED)
+			}
+			Convey("When creating a rollback plan", func() {
+				rg, err := g.RollbackPlan()
+				Convey("It should restore the previous version of '2' and recreate '5'", func() {
+					So(err, ShouldBeNil)
+					So(len(rg.Changes), ShouldEqual, 2)
+					So(rg.Changes[0].GetID(), ShouldEqual, "2")
+					So(rg.Changes[0].GetAction(), ShouldEqual, ACTIONUPDATE)
+					So(rg.Changes[0].(*testComponent).TestVal, ShouldEqual, 2)
+					So(rg.Changes[1].GetID(), ShouldEqual, "5")
+					So(rg.Changes[1].GetAction(), ShouldEqual, ACTIONCREATE)
+					So(len(rg.Edges), ShouldEqual, 4)
+				})
+			})
+		})
+
 		Convey("That has changes on its verticies", func() {
 			eg := New()
 			_ = eg.AddComponent(&testComponent{Name: "1", TestVal: 1})
@@ -445,6 +906,15 @@ func TestGraph(t *testing.T) {
 					So(g.Changes[3].GetID(), ShouldEqual, "4")
 					So(g.Changes[3].GetAction(), ShouldEqual, ACTIONDELETE)
 				})
+				Convey("It should return the correct order", func() {
+					sorted, err := g.TopologicalSort()
+					So(err, ShouldBeNil)
+					So(len(sorted), ShouldEqual, 4)
+					So(sorted[0].GetID(), ShouldEqual, "4")
+					So(sorted[1].GetID(), ShouldEqual, "2")
+					So(sorted[2].GetID(), ShouldEqual, "3")
+					So(sorted[3].GetID(), ShouldEqual, "1")
+				})
 				Convey("It should return the correct edges", func() {
 					So(len(g.Edges), ShouldEqual, 6)
 					So(g.Edges[0].Source, ShouldEqual, "2")
@@ -464,3 +934,1469 @@ func TestGraph(t *testing.T) {
 		})
 	})
 }
+
+func TestCriticalPath(t *testing.T) {
+	Convey("Given a graph of components with estimated durations", t, func() {
+		g := New()
+		g.AddComponent(&estimatedComponent{testComponent: testComponent{Name: "network"}, Duration: 5 * time.Second})
+		g.AddComponent(&estimatedComponent{testComponent: testComponent{Name: "web"}, Duration: 1500 * time.Millisecond})
+		g.AddComponent(&estimatedComponent{testComponent: testComponent{Name: "db"}, Duration: 30 * time.Second})
+		g.AddComponent(&testComponent{Name: "dns"})
+		g.Connect("network", "web")
+		g.Connect("network", "db")
+		g.Connect("web", "dns")
+		g.SetStartFinish()
+
+		Convey("When connecting components", func() {
+			Convey("The length of an edge should not depend on durations", func() {
+				So(g.LengthBetween("start", "network"), ShouldEqual, 1)
+				So(g.LengthBetween("network", "db"), ShouldEqual, 1)
+				So(g.LengthBetween("db", "end"), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When calculating the critical path", func() {
+			s, err := g.CriticalPath()
+			So(err, ShouldBeNil)
+			Convey("It should return the longest path and its duration", func() {
+				So(s.Path, ShouldResemble, []string{"network", "db"})
+				So(s.Duration, ShouldEqual, 35*time.Second)
+			})
+			Convey("It should return the slack of every component", func() {
+				So(len(s.Slack), ShouldEqual, 4)
+				So(s.Slack["network"], ShouldEqual, 0)
+				So(s.Slack["db"], ShouldEqual, 0)
+				So(s.Slack["web"], ShouldEqual, 28500*time.Millisecond)
+				So(s.Slack["dns"], ShouldEqual, 28500*time.Millisecond)
+			})
+		})
+
+		Convey("When calculating the critical path of a graph without a start vertex", func() {
+			ng := New()
+			ng.AddComponent(&estimatedComponent{testComponent: testComponent{Name: "network"}, Duration: 200 * time.Millisecond})
+			ng.AddComponent(&estimatedComponent{testComponent: testComponent{Name: "db", Deps: []string{"network"}}, Duration: 300 * time.Millisecond})
+			ng.AddComponent(&testComponent{Name: "dns", Deps: []string{"db"}})
+			So(ng.BuildDependencyEdges(false), ShouldBeNil)
+
+			s, err := ng.CriticalPath()
+			So(err, ShouldBeNil)
+			Convey("It should include the duration of every component, and no time for components without an estimate", func() {
+				So(s.Path, ShouldResemble, []string{"network", "db"})
+				So(s.Duration, ShouldEqual, 500*time.Millisecond)
+				So(s.Slack["dns"], ShouldEqual, 0)
+			})
+		})
+
+		Convey("When the estimated durations change", func() {
+			g.Component("web").(*estimatedComponent).Duration = time.Minute
+			s, err := g.CriticalPath()
+			So(err, ShouldBeNil)
+			Convey("It should use the current durations", func() {
+				So(s.Path, ShouldResemble, []string{"network", "web"})
+				So(s.Duration, ShouldEqual, 65*time.Second)
+			})
+		})
+
+		Convey("When calculating the critical path of a graph with a cycle", func() {
+			g.connect("dns", "network")
+			_, err := g.CriticalPath()
+			Convey("It should error", func() {
+				So(err, ShouldHaveSameTypeAs, &CycleError{})
+			})
+		})
+	})
+}
+
+func TestImpact(t *testing.T) {
+	Convey("Given a graph", t, func() {
+		g := New()
+		for _, id := range []string{"sg", "i1", "i2", "lb", "dns", "other"} {
+			g.AddComponent(&testComponent{Name: id})
+		}
+		g.Connect("sg", "i1")
+		g.Connect("sg", "i2")
+		g.Connect("i1", "lb")
+		g.Connect("i2", "lb")
+		g.Connect("lb", "dns")
+		g.Connect("other", "dns")
+		g.SetStartFinish()
+
+		ids := func(n *Neighbours) []string {
+			var s []string
+			for _, c := range *n {
+				s = append(s, c.GetID())
+			}
+			return s
+		}
+
+		Convey("When getting the descendants of a component", func() {
+			Convey("It should return all components that depend on it", func() {
+				So(ids(g.Descendants("sg")), ShouldResemble, []string{"i1", "i2", "lb", "dns"})
+				So(ids(g.Descendants("dns")), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When getting the ancestors of a component", func() {
+			Convey("It should return all components it depends on", func() {
+				So(ids(g.Ancestors("dns")), ShouldResemble, []string{"lb", "other", "i1", "i2", "sg"})
+				So(ids(g.Ancestors("sg")), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When getting the impact of changing components", func() {
+			impact := g.ImpactOf("i1", "other")
+			Convey("It should return all affected components with their depth", func() {
+				So(len(impact), ShouldEqual, 2)
+				So(impact[0].Component.GetID(), ShouldEqual, "lb")
+				So(impact[0].Depth, ShouldEqual, 1)
+				So(impact[1].Component.GetID(), ShouldEqual, "dns")
+				So(impact[1].Depth, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When getting the impact of a component that depends on another changed component", func() {
+			impact := g.ImpactOf("sg", "lb")
+			Convey("It should not include the changed components", func() {
+				So(len(impact), ShouldEqual, 3)
+				So(impact[0].Component.GetID(), ShouldEqual, "i1")
+				So(impact[1].Component.GetID(), ShouldEqual, "i2")
+				So(impact[2].Component.GetID(), ShouldEqual, "dns")
+				So(impact[2].Depth, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestTarget(t *testing.T) {
+	Convey("Given a diffed graph", t, func() {
+		og := New()
+		og.AddComponent(genericComponent("net", map[string]interface{}{"cidr": "10.0.0.0/16"}))
+		og.AddComponent(genericComponent("old1", nil))
+		og.AddComponent(genericComponent("old2", map[string]interface{}{"_dependencies": []string{"old1"}}))
+
+		ng := New()
+		ng.AddComponent(genericComponent("net", map[string]interface{}{"cidr": "10.1.0.0/16"}))
+		ng.AddComponent(genericComponent("web", map[string]interface{}{"_dependencies": []string{"net"}}))
+		ng.AddComponent(genericComponent("dns", map[string]interface{}{"_dependencies": []string{"web"}}))
+		ng.AddComponent(genericComponent("other", nil))
+
+		g, err := ng.DiffWithChangelog(og)
+		So(err, ShouldBeNil)
+
+		ids := func(cg ComponentGroup) []string {
+			var s []string
+			for _, c := range cg {
+				s = append(s, c.GetID())
+			}
+			return s
+		}
+
+		Convey("When targeting a created component", func() {
+			tg, excluded, err := g.Target("web")
+			So(err, ShouldBeNil)
+			Convey("It should include the component and the changes it depends on", func() {
+				So(ids(tg.Changes), ShouldResemble, []string{"net", "web"})
+				So(ids(excluded), ShouldResemble, []string{"dns", "other", "old1", "old2"})
+				So(len(tg.Components), ShouldEqual, 3)
+			})
+			Convey("It should rebuild the edges", func() {
+				So(len(tg.Edges), ShouldEqual, 3)
+				So(tg.Connected("start", "net"), ShouldBeTrue)
+				So(tg.Connected("net", "web"), ShouldBeTrue)
+				So(tg.Connected("web", "end"), ShouldBeTrue)
+			})
+			Convey("It should only include the changelog of the included changes", func() {
+				So(len(tg.Changelog), ShouldBeGreaterThan, 0)
+				for _, change := range tg.Changelog {
+					So(change.Path[0], ShouldBeIn, []string{"net", "web"})
+				}
+			})
+			Convey("It should not modify the original graph", func() {
+				So(len(g.Changes), ShouldEqual, 6)
+				So(g.Connected("web", "dns"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When targeting a deleted component", func() {
+			tg, excluded, err := g.Target("old1")
+			So(err, ShouldBeNil)
+			Convey("It should include the deletion of its dependents", func() {
+				So(ids(tg.Changes), ShouldResemble, []string{"old1", "old2"})
+				So(len(excluded), ShouldEqual, 4)
+				So(tg.Connected("start", "old2"), ShouldBeTrue)
+				So(tg.Connected("old2", "old1"), ShouldBeTrue)
+				So(tg.Connected("old1", "end"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When targeting a component that does not exist", func() {
+			_, _, err := g.Target("missing")
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Could not find change: missing")
+			})
+		})
+	})
+}
+
+func TestSubgraph(t *testing.T) {
+	Convey("Given a graph", t, func() {
+		g := New()
+		g.AddComponent(genericComponent("network", map[string]interface{}{"_component": "network"}))
+		g.AddComponent(genericComponent("subnet", map[string]interface{}{"_component": "subnet"}))
+		g.AddComponent(genericComponent("web", nil))
+		g.AddComponent(genericComponent("db", nil))
+		g.Connect("network", "subnet")
+		g.Connect("subnet", "web")
+		g.Connect("subnet", "db")
+		g.Connect("web", "db")
+		g.SetStartFinish()
+
+		instances := func(c Component) bool {
+			return c.GetType() == "instance"
+		}
+
+		Convey("When extracting a subgraph", func() {
+			sg := g.Subgraph(instances)
+			Convey("It should only contain matching components and the edges between them", func() {
+				So(len(sg.Components), ShouldEqual, 2)
+				So(sg.HasComponent("web"), ShouldBeTrue)
+				So(sg.HasComponent("db"), ShouldBeTrue)
+				So(len(sg.Edges), ShouldEqual, 2)
+				So(sg.Connected("web", "db"), ShouldBeTrue)
+				So(sg.Connected("db", "end"), ShouldBeTrue)
+			})
+			Convey("It should not modify the original graph", func() {
+				So(len(g.Components), ShouldEqual, 4)
+				So(len(g.Edges), ShouldEqual, 6)
+			})
+		})
+
+		Convey("When extracting a collapsed subgraph", func() {
+			sg := g.CollapsedSubgraph(instances)
+			Convey("It should connect the origins of removed vertices to their neighbours", func() {
+				So(len(sg.Components), ShouldEqual, 2)
+				So(len(sg.Edges), ShouldEqual, 4)
+				So(sg.Connected("start", "web"), ShouldBeTrue)
+				So(sg.Connected("start", "db"), ShouldBeTrue)
+				So(sg.Connected("web", "db"), ShouldBeTrue)
+				So(sg.Connected("db", "end"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When extracting a collapsed subgraph that removes every vertex on a path", func() {
+			sg := g.CollapsedSubgraph(func(c Component) bool {
+				return c.GetID() == "web"
+			})
+			Convey("It should not connect start to end", func() {
+				So(len(sg.Components), ShouldEqual, 1)
+				So(sg.Connected("start", "web"), ShouldBeTrue)
+				So(sg.Connected("web", "end"), ShouldBeTrue)
+				So(sg.Connected("start", "end"), ShouldBeFalse)
+				So(len(sg.Edges), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When extracting a subgraph of a diffed graph", func() {
+			g.Changes = g.Components
+			g.Components = []Component{genericComponent("web", nil)}
+			sg := g.Subgraph(func(c Component) bool {
+				return c.GetID() == "web"
+			})
+			Convey("It should filter both components and changes", func() {
+				So(len(sg.Components), ShouldEqual, 1)
+				So(len(sg.Changes), ShouldEqual, 1)
+				So(len(sg.Edges), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestMerge(t *testing.T) {
+	Convey("Given two graphs that share a component", t, func() {
+		network := New()
+		network.AddComponent(genericComponent("vpc", nil))
+		network.AddComponent(genericComponent("subnet", map[string]interface{}{"cidr": "10.0.0.0/24"}))
+		network.Connect("vpc", "subnet")
+		network.SetStartFinish()
+
+		compute := New()
+		compute.AddComponent(genericComponent("subnet", map[string]interface{}{"tier": "public"}))
+		compute.AddComponent(genericComponent("web", nil))
+		compute.Connect("subnet", "web")
+		compute.SetStartFinish()
+
+		Convey("When merging them", func() {
+			_, conflicts, err := Merge(network, compute)
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "1 error(s) occurred: Component already exists: subnet")
+				So(len(conflicts), ShouldEqual, 1)
+				So(conflicts[0].ComponentID, ShouldEqual, "subnet")
+			})
+		})
+
+		Convey("When merging them preferring the left component", func() {
+			g, conflicts, err := MergeWithPolicy(MERGELEFT, network, compute)
+			So(err, ShouldBeNil)
+			Convey("It should keep the first component", func() {
+				So(len(g.Components), ShouldEqual, 3)
+				So(g.Component("subnet"), ShouldEqual, network.Component("subnet"))
+				So(conflicts[0].Resolved, ShouldEqual, network.Component("subnet"))
+			})
+			Convey("It should union all edges and recalculate start and end", func() {
+				So(len(g.Edges), ShouldEqual, 4)
+				So(g.Connected("start", "vpc"), ShouldBeTrue)
+				So(g.Connected("vpc", "subnet"), ShouldBeTrue)
+				So(g.Connected("subnet", "web"), ShouldBeTrue)
+				So(g.Connected("web", "end"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When merging them preferring the right component", func() {
+			g, _, err := MergeWithPolicy(MERGERIGHT, network, compute)
+			So(err, ShouldBeNil)
+			Convey("It should keep the last component", func() {
+				So(g.Component("subnet"), ShouldEqual, compute.Component("subnet"))
+			})
+		})
+
+		Convey("When merging them by updating the left component", func() {
+			g, conflicts, err := MergeWithPolicy(MERGEUPDATE, network, compute)
+			So(err, ShouldBeNil)
+			Convey("It should combine the values of both components", func() {
+				subnet := *g.Component("subnet").(*GenericComponent)
+				So(subnet["cidr"], ShouldEqual, "10.0.0.0/24")
+				So(subnet["tier"], ShouldEqual, "public")
+				So(conflicts[0].Resolved, ShouldEqual, g.Component("subnet"))
+			})
+			Convey("It should not modify the merged graphs", func() {
+				So((*network.Component("subnet").(*GenericComponent))["tier"], ShouldBeNil)
+			})
+		})
+
+		Convey("When merging them with a resolver", func() {
+			g, conflicts, err := MergeWithResolver(func(left, right Component) (Component, error) {
+				c, err := ResolveUpdate(left, right)
+				if err != nil {
+					return nil, err
+				}
+				c.SetAction(ACTIONUPDATE)
+				return c, nil
+			}, network, compute)
+			So(err, ShouldBeNil)
+			Convey("It should keep the resolved component", func() {
+				subnet := g.Component("subnet")
+				So(subnet.GetAction(), ShouldEqual, ACTIONUPDATE)
+				So((*subnet.(*GenericComponent))["tier"], ShouldEqual, "public")
+				So(conflicts[0].Left, ShouldEqual, network.Component("subnet"))
+				So(conflicts[0].Right, ShouldEqual, compute.Component("subnet"))
+				So(conflicts[0].Resolved, ShouldEqual, subnet)
+			})
+		})
+
+		Convey("When merging them with a resolver that fails", func() {
+			_, conflicts, err := MergeWithResolver(func(left, right Component) (Component, error) {
+				return nil, errors.New("Values differ")
+			}, network, compute)
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "1 error(s) occurred: Component subnet: Values differ")
+				So(len(conflicts), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When merging them with an unknown policy", func() {
+			_, _, err := MergeWithPolicy("unknown", network, compute)
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestClone(t *testing.T) {
+	Convey("Given a graph", t, func() {
+		g := New()
+		g.Options = map[string]interface{}{"regions": []interface{}{"eu-west-1"}}
+		g.AddComponent(genericComponent("web", map[string]interface{}{"ports": []interface{}{80}, "_tags": map[string]interface{}{"env": "dev"}}))
+		g.AddComponent(&testComponent{Name: "db", Deps: []string{"web"}, TestVal: 1})
+		g.Connect("web", "db")
+		g.SetStartFinish()
+		g.Changelog = diff.Changelog{{Type: diff.UPDATE, Path: []string{"web", "ports"}, From: []interface{}{80}, To: []interface{}{443}}}
+
+		Convey("When cloning it", func() {
+			ng, err := g.Clone()
+			So(err, ShouldBeNil)
+			Convey("It should copy all values", func() {
+				So(ng.Components, ShouldResemble, g.Components)
+				So(ng.Edges, ShouldResemble, g.Edges)
+				So(ng.Options, ShouldResemble, g.Options)
+				So(ng.Changelog, ShouldResemble, g.Changelog)
+				So(ng.Component("db"), ShouldHaveSameTypeAs, &testComponent{})
+				So(ng.Connected("web", "db"), ShouldBeTrue)
+			})
+			Convey("It should not share any values with the original graph", func() {
+				web := *ng.Component("web").(*GenericComponent)
+				web["ports"].([]interface{})[0] = 8080
+				web["_tags"].(map[string]interface{})["env"] = "prod"
+				ng.Component("db").SetAction(ACTIONDELETE)
+				ng.Options["regions"].([]interface{})[0] = "us-east-1"
+				ng.Changelog[0].Path[0] = "db"
+				ng.Edges[0].Length = 10
+
+				So(g.Component("web").GetTag("env"), ShouldEqual, "dev")
+				So((*g.Component("web").(*GenericComponent))["ports"], ShouldResemble, []interface{}{80})
+				So(g.Component("db").GetAction(), ShouldEqual, "")
+				So(g.Options["regions"], ShouldResemble, []interface{}{"eu-west-1"})
+				So(g.Changelog[0].Path[0], ShouldEqual, "web")
+				So(g.Edges[0].Length, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When safely diffing it", func() {
+			og := New()
+			og.AddComponent(genericComponent("web", map[string]interface{}{"ports": []interface{}{443}}))
+			og.AddComponent(&testComponent{Name: "old"})
+
+			dg, err := g.SafeDiffWithChangelog(og)
+			So(err, ShouldBeNil)
+			Convey("It should produce the diffed graph", func() {
+				So(len(dg.Changes), ShouldEqual, 3)
+				So(dg.ComponentAll("web").GetAction(), ShouldEqual, ACTIONUPDATE)
+				So(dg.ComponentAll("db").GetAction(), ShouldEqual, ACTIONCREATE)
+				So(dg.ComponentAll("old").GetAction(), ShouldEqual, ACTIONDELETE)
+				So(len(dg.Changelog), ShouldBeGreaterThan, 0)
+			})
+			Convey("It should not modify either graph", func() {
+				So(g.Component("web").GetAction(), ShouldEqual, "")
+				So(g.Component("db").GetState(), ShouldEqual, "")
+				So(og.Component("old").GetAction(), ShouldEqual, "")
+				So(len(og.Components), ShouldEqual, 2)
+				So(dg.Components[0], ShouldNotPointTo, og.Components[0])
+			})
+		})
+	})
+}
+
+func TestPatch(t *testing.T) {
+	Register("test", "registered", func() Component {
+		return &registeredComponent{}
+	})
+
+	Convey("Given a graph", t, func() {
+		previous := func() *Graph {
+			g := New()
+			g.AddComponent(genericComponent("web", map[string]interface{}{"size": "1", "ports": []interface{}{80, 22}, "labels": map[string]interface{}{"env": "dev"}}))
+			g.AddComponent(&testComponent{Name: "db", TestVal: 1})
+			g.AddComponent(genericComponent("old", map[string]interface{}{"size": "1"}))
+			g.Connect("old", "web")
+			g.SetStartFinish()
+			return g
+		}
+
+		Convey("When applying the changelog of a diff", func() {
+			g := New()
+			g.AddComponent(genericComponent("web", map[string]interface{}{"size": "2", "ports": []interface{}{443, 22}, "labels": map[string]interface{}{"env": "prod"}}))
+			g.AddComponent(&testComponent{Name: "db", TestVal: 2})
+			g.AddComponent(genericComponent("cache", map[string]interface{}{"size": "1"}))
+
+			dg, err := g.DiffWithChangelog(previous())
+			So(err, ShouldBeNil)
+
+			pg := previous()
+
+			results := pg.Patch(dg.Changelog)
+
+			Convey("It should apply every change", func() {
+				So(len(results), ShouldEqual, len(dg.Changelog))
+				for _, r := range results {
+					So(r.Err, ShouldBeNil)
+					So(r.Applied, ShouldBeTrue)
+				}
+			})
+			Convey("It should update existing components", func() {
+				web := *pg.Component("web").(*GenericComponent)
+				So(web["size"], ShouldEqual, "2")
+				So(web["ports"], ShouldResemble, []interface{}{float64(443), float64(22)})
+				So(web["labels"], ShouldResemble, map[string]interface{}{"env": "prod"})
+				So(pg.Component("db").(*testComponent).TestVal, ShouldEqual, 2)
+			})
+			Convey("It should create new components", func() {
+				cache := pg.Component("cache")
+				So(cache, ShouldNotBeNil)
+				So(cache.GetType(), ShouldEqual, "instance")
+				So(cache.GetProvider(), ShouldEqual, "test")
+				So((*cache.(*GenericComponent))["size"], ShouldEqual, "1")
+			})
+			Convey("It should delete removed components and their edges", func() {
+				So(pg.HasComponent("old"), ShouldBeFalse)
+				So(pg.Connected("old", "web"), ShouldBeFalse)
+				So(len(pg.Components), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When applying the changelog of a diff that creates a registered component", func() {
+			g := previous()
+			g.AddComponent(&registeredComponent{testComponent: testComponent{Name: "queue", TestVal: 3}, ID: "queue", Provider: "test", Type: "registered"})
+
+			dg, err := g.DiffWithChangelog(previous())
+			So(err, ShouldBeNil)
+
+			pg := previous()
+
+			results := pg.Patch(dg.Changelog)
+
+			Convey("It should create the component with its registered type", func() {
+				for _, r := range results {
+					So(r.Err, ShouldBeNil)
+					So(r.Applied, ShouldBeTrue)
+				}
+				queue, ok := pg.Component("queue").(*registeredComponent)
+				So(ok, ShouldBeTrue)
+				So(queue.GetID(), ShouldEqual, "queue")
+				So(queue.TestVal, ShouldEqual, 3)
+				So(queue.Provider, ShouldEqual, "test")
+				So(queue.Type, ShouldEqual, "registered")
+			})
+		})
+
+		Convey("When applying the changelog of a diff that deletes a registered component", func() {
+			pg := previous()
+			pg.AddComponent(&registeredComponent{testComponent: testComponent{Name: "queue", TestVal: 3}, ID: "queue", Provider: "test", Type: "registered"})
+			pg.Connect("queue", "db")
+
+			dg, err := previous().DiffWithChangelog(pg)
+			So(err, ShouldBeNil)
+
+			results := pg.Patch(dg.Changelog)
+
+			Convey("It should delete the component and its edges", func() {
+				for _, r := range results {
+					So(r.Err, ShouldBeNil)
+					So(r.Applied, ShouldBeTrue)
+				}
+				So(pg.HasComponent("queue"), ShouldBeFalse)
+				So(pg.Connected("queue", "db"), ShouldBeFalse)
+			})
+		})
+
+		Convey("When applying changes that create a component of an unknown type", func() {
+			g := previous()
+
+			results := g.Patch(diff.Changelog{
+				{Type: diff.CREATE, Path: []string{"queue", "test_val"}, To: 3},
+			})
+
+			Convey("It should not create the component", func() {
+				So(results[0].Applied, ShouldBeFalse)
+				So(results[0].Err.Error(), ShouldEqual, "Could not find the type of created component: queue")
+				So(g.HasComponent("queue"), ShouldBeFalse)
+			})
+		})
+
+		Convey("When applying changes that can not be applied", func() {
+			g := previous()
+			db := g.Component("db")
+
+			results := g.Patch(diff.Changelog{
+				{Type: diff.UPDATE, Path: []string{"web", "size"}, From: "1", To: "3"},
+				{Type: diff.UPDATE, Path: []string{"db", "test_val"}, From: 1, To: 2},
+				{Type: diff.UPDATE, Path: []string{"web", "size", "x"}, From: "1", To: "3"},
+				{Type: diff.UPDATE, Path: []string{"web", "ports", "x"}, From: 80, To: 8080},
+				{Type: diff.UPDATE, Path: []string{"missing", "size"}, From: "1", To: "2"},
+				{Type: diff.UPDATE, Path: []string{"web"}, From: nil, To: "2"},
+				{Type: diff.UPDATE, Path: []string{"db", "test_val"}, From: 5, To: 6},
+				{Type: diff.DELETE, Path: []string{"web", "ports", "1"}, From: 8080},
+			})
+
+			Convey("It should report the result of every change", func() {
+				So(len(results), ShouldEqual, 8)
+				So(results[0].Applied, ShouldBeTrue)
+				So(results[0].Err, ShouldBeNil)
+				So(results[1].Applied, ShouldBeTrue)
+				So(results[1].Err, ShouldBeNil)
+				So(results[2].Applied, ShouldBeFalse)
+				So(results[2].Err.Error(), ShouldEqual, "Could not patch value: x")
+				So(results[3].Applied, ShouldBeFalse)
+				So(results[3].Err.Error(), ShouldEqual, "Could not patch slice element: x")
+				So(results[4].Applied, ShouldBeFalse)
+				So(results[4].Err.Error(), ShouldEqual, "Component does not exist: missing")
+				So(results[5].Applied, ShouldBeFalse)
+				So(results[5].Err.Error(), ShouldEqual, "Change does not reference a component value: web")
+				So(results[6].Applied, ShouldBeFalse)
+				So(results[6].Err, ShouldHaveSameTypeAs, &ConflictError{})
+				So(results[6].Err.Error(), ShouldEqual, "Value has changed since the changelog was created: db.test_val")
+				So(results[7].Applied, ShouldBeFalse)
+				So(results[7].Err, ShouldHaveSameTypeAs, &ConflictError{})
+			})
+			Convey("It should still apply the valid changes", func() {
+				So((*g.Component("web").(*GenericComponent))["size"], ShouldEqual, "3")
+				So((*g.Component("web").(*GenericComponent))["ports"], ShouldResemble, []interface{}{float64(80), float64(22)})
+				So(g.Component("db").(*testComponent).TestVal, ShouldEqual, 2)
+				So(g.Component("db"), ShouldPointTo, db)
+				So(g.HasComponent("missing"), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Given a valid graph", t, func() {
+		g := New()
+		g.AddComponent(&testComponent{Name: "a", Action: ACTIONCREATE})
+		g.AddComponent(&testComponent{Name: "b", Action: ACTIONUPDATE, Deps: []string{"a"}})
+		g.Connect("a", "b")
+		g.SetStartFinish()
+
+		Convey("When validating it", func() {
+			err := g.Validate()
+			Convey("It should not error", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given an invalid graph", t, func() {
+		g := New()
+		g.AddComponent(&testComponent{Name: "a", Action: "recreate"})
+		g.AddComponent(&testComponent{Name: "b", Deps: []string{"a", "missing"}})
+		g.AddComponent(&invalidComponent{testComponent{Name: "c"}})
+		g.Components = append(g.Components, &testComponent{Name: "a"})
+		g.Connect("a", "b")
+		g.Connect("b", "a")
+		g.Edges = append(g.Edges, Edge{Source: "c", Destination: "ghost"})
+		g.SetStartFinish()
+
+		Convey("When validating it", func() {
+			err := g.Validate()
+			Convey("It should report every problem", func() {
+				So(err, ShouldNotBeNil)
+
+				errs := err.(*MultiError).Errors
+				So(len(errs), ShouldEqual, 6)
+
+				var msgs []string
+				for _, e := range errs {
+					So(e, ShouldHaveSameTypeAs, &ComponentError{})
+					msgs = append(msgs, e.Error())
+				}
+
+				So(msgs, ShouldResemble, []string{
+					"Component a: Duplicate component id",
+					"Component a: Unknown action: recreate",
+					"Component b: Depends on a component that does not exist: missing",
+					"Component c: Invalid value",
+					"Component ghost: Edge references a component that does not exist",
+					"Component a: Graph contains a cycle: a -> b -> a",
+				})
+				So(errs[5].(*ComponentError).Err, ShouldHaveSameTypeAs, &CycleError{})
+			})
+		})
+	})
+}
+
+func TestSafeGraph(t *testing.T) {
+	Convey("Given a safe graph", t, func() {
+		sg := NewSafeGraph(New())
+		sg.AddComponent(genericComponent("root", nil))
+
+		Convey("When reading and writing it concurrently", func() {
+			var wg sync.WaitGroup
+
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+
+				go func(i int) {
+					defer wg.Done()
+					id := "component-" + strconv.Itoa(i)
+					sg.AddComponent(genericComponent(id, nil))
+					sg.Connect("root", id)
+					sg.SetState(id, STATECOMPLETED)
+					sg.UpdateComponent(genericComponent(id, map[string]interface{}{"updated": true}))
+				}(i)
+
+				go func(i int) {
+					defer wg.Done()
+					id := "component-" + strconv.Itoa(i)
+					sg.Component(id)
+					sg.Connected("root", id)
+					sg.Neighbours("root")
+					sg.Origins(id)
+					sg.GetComponents()
+					sg.ToJSON()
+				}(i)
+			}
+
+			wg.Wait()
+
+			Convey("It should apply all writes", func() {
+				So(len(sg.GetComponents()), ShouldEqual, 11)
+				So(len(*sg.Neighbours("root")), ShouldEqual, 10)
+				So((*sg.Component("component-5").(*GenericComponent))["updated"], ShouldEqual, true)
+			})
+		})
+
+		Convey("When disconnecting and deleting components concurrently", func() {
+			for i := 0; i < 10; i++ {
+				id := "component-" + strconv.Itoa(i)
+				sg.AddComponent(genericComponent(id, nil))
+				sg.Connect("root", id)
+			}
+
+			var wg sync.WaitGroup
+
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+
+				go func(i int) {
+					defer wg.Done()
+					id := "component-" + strconv.Itoa(i)
+					sg.DisconnectComponent(id)
+					sg.DeleteComponent(sg.Component(id))
+				}(i)
+
+				go func() {
+					defer wg.Done()
+					sg.Read(func(g *Graph) {
+						for _, c := range g.Components {
+							g.Neighbours(c.GetID())
+						}
+					})
+				}()
+			}
+
+			wg.Wait()
+
+			Convey("It should apply all writes", func() {
+				So(len(sg.GetComponents()), ShouldEqual, 1)
+				So(len(*sg.Neighbours("root")), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestNodeStack(t *testing.T) {
+	Convey("Given a node stack", t, func() {
+		var n NodeStack
+		n.Append([]Component{&testComponent{Name: "a"}, &testComponent{Name: "b"}})
+		n.Prepend([]Component{&testComponent{Name: "c"}})
+
+		Convey("When popping all components", func() {
+			var ids []string
+			for !n.Empty() {
+				ids = append(ids, n.Pop().GetID())
+			}
+			Convey("It should return them last in first out", func() {
+				So(ids, ShouldResemble, []string{"b", "a", "c"})
+				So(n.Pop(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a node queue", t, func() {
+		var n NodeQueue
+		n.Append([]Component{&testComponent{Name: "a"}, &testComponent{Name: "b"}})
+		n.Append([]Component{&testComponent{Name: "c"}})
+
+		Convey("When popping all components", func() {
+			var ids []string
+			for !n.Empty() {
+				ids = append(ids, n.Pop().GetID())
+			}
+			Convey("It should return them first in first out", func() {
+				So(ids, ShouldResemble, []string{"a", "b", "c"})
+				So(n.Pop(), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestTraversal(t *testing.T) {
+	Convey("Given a graph", t, func() {
+		g := New()
+		for _, id := range []string{"a", "b", "c", "d"} {
+			g.AddComponent(&testComponent{Name: id})
+		}
+		g.Connect("a", "b")
+		g.Connect("a", "c")
+		g.Connect("b", "d")
+		g.Connect("c", "d")
+		g.SetStartFinish()
+
+		var visited []string
+		visit := func(c Component) bool {
+			visited = append(visited, c.GetID())
+			return true
+		}
+
+		Convey("When traversing it depth first", func() {
+			g.DFS("start", visit)
+			Convey("It should visit all components depth first", func() {
+				So(visited, ShouldResemble, []string{"a", "b", "d", "c"})
+			})
+		})
+
+		Convey("When traversing it breadth first", func() {
+			g.BFS("start", visit)
+			Convey("It should visit all components breadth first", func() {
+				So(visited, ShouldResemble, []string{"a", "b", "c", "d"})
+			})
+		})
+
+		Convey("When traversing it in reverse", func() {
+			g.ReverseDFS("end", visit)
+			So(visited, ShouldResemble, []string{"d", "b", "a", "c"})
+
+			visited = nil
+			g.ReverseBFS("d", visit)
+			So(visited, ShouldResemble, []string{"d", "b", "c", "a"})
+		})
+
+		Convey("When stopping the traversal early", func() {
+			g.DFS("a", func(c Component) bool {
+				visited = append(visited, c.GetID())
+				return c.GetID() != "b"
+			})
+			Convey("It should not visit any more components", func() {
+				So(visited, ShouldResemble, []string{"a", "b"})
+			})
+		})
+
+		Convey("When traversing a graph with a cycle", func() {
+			g.connect("d", "a")
+			g.DFS("start", visit)
+			Convey("It should visit every component once", func() {
+				So(visited, ShouldResemble, []string{"a", "b", "d", "c"})
+			})
+		})
+	})
+}
+
+func TestJournal(t *testing.T) {
+	Convey("Given a journal of a diffed graph", t, func() {
+		dir, err := ioutil.TempDir("", "journal")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "journal")
+
+		ng := New()
+		ng.AddComponent(genericComponent("a", nil))
+		ng.AddComponent(genericComponent("b", map[string]interface{}{"_dependencies": []string{"a"}}))
+		ng.AddComponent(genericComponent("c", map[string]interface{}{"_dependencies": []string{"b"}}))
+		ng.AddComponent(genericComponent("d", nil))
+
+		g, err := ng.Diff(New())
+		So(err, ShouldBeNil)
+
+		j, err := CreateJournal(path, g)
+		So(err, ShouldBeNil)
+
+		So(j.SetState(g.ComponentAll("a"), STATERUNNING), ShouldBeNil)
+		So(j.SetState(g.ComponentAll("d"), STATERUNNING), ShouldBeNil)
+		So(j.SetState(g.ComponentAll("a"), STATECOMPLETED), ShouldBeNil)
+		So(j.SetState(g.ComponentAll("d"), STATECOMPLETED), ShouldBeNil)
+		So(j.SetState(g.ComponentAll("b"), STATERUNNING), ShouldBeNil)
+		So(j.SetAction(g.ComponentAll("c"), ACTIONNONE), ShouldBeNil)
+		So(j.Close(), ShouldBeNil)
+
+		Convey("When creating a journal that already exists", func() {
+			_, err := CreateJournal(path, g)
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When resuming the graph after a crash", func() {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+			So(err, ShouldBeNil)
+			f.WriteString(`{"type":"state","compon`)
+			f.Close()
+
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+
+			rg := New()
+			plan, running, err := rg.Resume(bytes.NewReader(data))
+			So(err, ShouldBeNil)
+
+			Convey("It should replay all transitions", func() {
+				So(len(rg.Changes), ShouldEqual, 4)
+				So(rg.ComponentAll("a").GetState(), ShouldEqual, STATECOMPLETED)
+				So(rg.ComponentAll("b").GetState(), ShouldEqual, STATERUNNING)
+				So(rg.ComponentAll("c").GetState(), ShouldEqual, STATEWAITING)
+				So(rg.ComponentAll("c").GetAction(), ShouldEqual, ACTIONNONE)
+			})
+			Convey("It should return a plan of all changes that have not completed", func() {
+				So(len(plan.Changes), ShouldEqual, 2)
+				So(plan.Changes[0].GetID(), ShouldEqual, "b")
+				So(plan.Changes[1].GetID(), ShouldEqual, "c")
+				So(len(plan.Edges), ShouldEqual, 3)
+				So(plan.Connected("start", "b"), ShouldBeTrue)
+				So(plan.Connected("b", "c"), ShouldBeTrue)
+				So(plan.Connected("c", "end"), ShouldBeTrue)
+			})
+			Convey("It should return all running changes", func() {
+				So(len(running), ShouldEqual, 1)
+				So(running[0].GetID(), ShouldEqual, "b")
+			})
+		})
+
+		Convey("When appending to the journal", func() {
+			j, err := OpenJournal(path)
+			So(err, ShouldBeNil)
+			So(j.SetState(g.ComponentAll("b"), STATECOMPLETED), ShouldBeNil)
+			So(j.Close(), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+
+			plan, running, err := New().Resume(bytes.NewReader(data))
+			So(err, ShouldBeNil)
+
+			Convey("It should include the new transitions when resuming", func() {
+				So(len(plan.Changes), ShouldEqual, 1)
+				So(plan.Changes[0].GetID(), ShouldEqual, "c")
+				So(running, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When resuming from an invalid journal", func() {
+			_, _, err := New().Resume(strings.NewReader(`{"type":"state","component_id":"a","value":"completed"}` + "\n"))
+			Convey("It should error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Journal does not start with a graph")
+			})
+		})
+	})
+}
+
+func TestLoad(t *testing.T) {
+	Register("test", "registered", func() Component {
+		return &registeredComponent{}
+	})
+
+	Convey("Given a serialised graph with registered and unregistered component types", t, func() {
+		g := New()
+		_ = g.AddComponent(&registeredComponent{testComponent: testComponent{Name: "1", Deps: []string{"2"}, TestVal: 1}, ID: "1", Provider: "test", Type: "registered"})
+		_ = g.AddComponent(&registeredComponent{testComponent: testComponent{Name: "2", TestVal: 2}, ID: "2", Provider: "test", Type: "unregistered"})
+		g.Changes = []Component{&registeredComponent{testComponent: testComponent{Name: "1", Action: ACTIONUPDATE}, ID: "1", Provider: "test", Type: "registered"}}
+		_ = g.Connect("2", "1")
+
+		data, err := g.ToJSON()
+		So(err, ShouldBeNil)
+
+		Convey("When unmarshalling the graph from json", func() {
+			var ng Graph
+			err := json.Unmarshal(data, &ng)
+			Convey("It should create components of their registered type", func() {
+				So(err, ShouldBeNil)
+				So(len(ng.Components), ShouldEqual, 2)
+				c, ok := ng.Component("1").(*registeredComponent)
+				So(ok, ShouldBeTrue)
+				So(c.TestVal, ShouldEqual, 1)
+				So(c.Dependencies(), ShouldResemble, []string{"2"})
+				So(len(ng.Changes), ShouldEqual, 1)
+				So(ng.Changes[0].GetAction(), ShouldEqual, ACTIONUPDATE)
+				So(ng.Connected("2", "1"), ShouldBeTrue)
+			})
+			Convey("It should load unregistered types as generic components", func() {
+				c, ok := ng.Component("2").(*GenericComponent)
+				So(ok, ShouldBeTrue)
+				So((*c)["test_val"], ShouldEqual, 2)
+			})
+		})
+
+		Convey("When loading the graph from a map", func() {
+			var m map[string]interface{}
+			_ = json.Unmarshal(data, &m)
+
+			ng := New()
+			err := ng.Load(m)
+			Convey("It should create components of their registered type", func() {
+				So(err, ShouldBeNil)
+				So(len(ng.Components), ShouldEqual, 2)
+				c, ok := ng.Component("1").(*registeredComponent)
+				So(ok, ShouldBeTrue)
+				So(c.Dependencies(), ShouldResemble, []string{"2"})
+				_, ok = ng.Component("2").(*GenericComponent)
+				So(ok, ShouldBeTrue)
+				_, ok = ng.Changes[0].(*registeredComponent)
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestReplace(t *testing.T) {
+	Convey("Given an existing graph with a component that requires replacement", t, func() {
+		eg := New()
+		_ = eg.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 1}})
+		_ = eg.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "2", Deps: []string{"1"}, TestVal: 1}})
+
+		Convey("When diffing a graph that destroys before creating", func() {
+			ng := New()
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 2}})
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "2", Deps: []string{"1"}, TestVal: 2}})
+			g, err := ng.Diff(eg)
+			Convey("It should replace the component", func() {
+				So(err, ShouldBeNil)
+				So(len(g.Changes), ShouldEqual, 3)
+				So(g.Changes[0].GetID(), ShouldEqual, "1")
+				So(g.Changes[0].GetAction(), ShouldEqual, ACTIONREPLACE)
+				So(g.Changes[1].GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+				So(g.Changes[1].GetAction(), ShouldEqual, ACTIONDELETE)
+				So(g.Changes[1].(*ReplacedComponent).Component.(*replaceableComponent).TestVal, ShouldEqual, 1)
+				So(g.Changes[2].GetID(), ShouldEqual, "2")
+				So(g.Changes[2].GetAction(), ShouldEqual, ACTIONUPDATE)
+			})
+			Convey("It should delete the previous version first", func() {
+				waves, err := g.Waves()
+				So(err, ShouldBeNil)
+				So(len(waves), ShouldEqual, 3)
+				So(waves[0][0].GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+				So(waves[1][0].GetID(), ShouldEqual, "1")
+				So(waves[2][0].GetID(), ShouldEqual, "2")
+			})
+		})
+
+		Convey("When diffing a graph that creates before destroying", func() {
+			ng := New()
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 2}, CBD: true})
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "2", Deps: []string{"1"}, TestVal: 2}})
+			g, err := ng.Diff(eg)
+			Convey("It should delete the previous version after the dependents are updated", func() {
+				So(err, ShouldBeNil)
+				So(len(g.Changes), ShouldEqual, 3)
+				waves, err := g.Waves()
+				So(err, ShouldBeNil)
+				So(len(waves), ShouldEqual, 3)
+				So(waves[0][0].GetID(), ShouldEqual, "1")
+				So(waves[1][0].GetID(), ShouldEqual, "2")
+				So(waves[2][0].GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+			})
+			Convey("It should serialise the previous version with its own id", func() {
+				data, err := json.Marshal(g.Changes[1])
+				So(err, ShouldBeNil)
+				So(string(data), ShouldContainSubstring, `"_component_id":"1::replaced"`)
+				So(string(data), ShouldContainSubstring, `"_replaces":"1"`)
+			})
+		})
+
+		Convey("When rolling back a completed replacement", func() {
+			ng := New()
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 2}})
+			_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "2", Deps: []string{"1"}, TestVal: 2}})
+			g, err := ng.Diff(eg)
+			So(err, ShouldBeNil)
+			for _, c := range g.Changes {
+				c.SetState(STATECOMPLETED)
+			}
+			rg, err := g.RollbackPlan()
+			Convey("It should delete the replacing version and create the previous version with its own id", func() {
+				So(err, ShouldBeNil)
+				So(len(rg.Changes), ShouldEqual, 3)
+				So(rg.Changes[0].GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+				So(rg.Changes[0].GetAction(), ShouldEqual, ACTIONDELETE)
+				So(rg.Changes[0].(*ReplacedComponent).Component.(*replaceableComponent).TestVal, ShouldEqual, 2)
+				So(rg.Changes[1].GetID(), ShouldEqual, "1")
+				So(rg.Changes[1].GetAction(), ShouldEqual, ACTIONCREATE)
+				So(rg.Changes[1].(*replaceableComponent).TestVal, ShouldEqual, 1)
+				So(rg.Changes[2].GetID(), ShouldEqual, "2")
+				So(rg.Changes[2].GetAction(), ShouldEqual, ACTIONUPDATE)
+			})
+			Convey("It should revert the changes in reverse order", func() {
+				waves, err := rg.Waves()
+				So(err, ShouldBeNil)
+				So(len(waves), ShouldEqual, 3)
+				So(waves[0][0].GetID(), ShouldEqual, "2")
+				So(waves[1][0].GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+				So(waves[2][0].GetID(), ShouldEqual, "1")
+			})
+		})
+	})
+
+	Convey("Given a diffed graph replacing a component that is not stateful", t, func() {
+		eg := New()
+		_ = eg.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 1}, Stateless: true})
+		ng := New()
+		_ = ng.AddComponent(&replaceableComponent{testComponent: testComponent{Name: "1", TestVal: 2}, Stateless: true})
+		g, err := ng.Diff(eg)
+		So(err, ShouldBeNil)
+		So(len(g.Changes), ShouldEqual, 2)
+
+		Convey("When rolling back the replacement", func() {
+			for _, c := range g.Changes {
+				c.SetState(STATECOMPLETED)
+			}
+			rg, err := g.RollbackPlan()
+			Convey("It should create the previous version again", func() {
+				So(err, ShouldBeNil)
+				So(len(rg.Changes), ShouldEqual, 2)
+				So(rg.Changes[1].GetID(), ShouldEqual, "1")
+				So(rg.Changes[1].GetAction(), ShouldEqual, ACTIONCREATE)
+				So(rg.Changes[1].(*replaceableComponent).TestVal, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a diffed graph replacing a registered component", t, func() {
+		Register("test", "replaceable", func() Component {
+			return &registeredReplaceableComponent{}
+		})
+
+		component := func(val int) *registeredReplaceableComponent {
+			return &registeredReplaceableComponent{registeredComponent{testComponent: testComponent{Name: "1", TestVal: val}, ID: "1", Provider: "test", Type: "replaceable"}}
+		}
+
+		eg := New()
+		_ = eg.AddComponent(component(1))
+		ng := New()
+		_ = ng.AddComponent(component(2))
+		g, err := ng.Diff(eg)
+		So(err, ShouldBeNil)
+
+		Convey("When serialising and loading it", func() {
+			data, err := g.ToJSON()
+			So(err, ShouldBeNil)
+
+			var lg Graph
+			err = json.Unmarshal(data, &lg)
+			So(err, ShouldBeNil)
+
+			var m map[string]interface{}
+			_ = json.Unmarshal(data, &m)
+
+			mg := New()
+			err = mg.Load(m)
+			So(err, ShouldBeNil)
+
+			Convey("It should load the previous version as a replaced component", func() {
+				for _, sg := range []*Graph{&lg, mg} {
+					So(len(sg.Changes), ShouldEqual, 2)
+					So(sg.Changes[0].GetID(), ShouldEqual, "1")
+					rc, ok := sg.Changes[1].(*ReplacedComponent)
+					So(ok, ShouldBeTrue)
+					So(rc.GetID(), ShouldEqual, "1"+REPLACEDSUFFIX)
+					So(rc.GetAction(), ShouldEqual, ACTIONDELETE)
+					So(rc.Component.(*registeredReplaceableComponent).TestVal, ShouldEqual, 1)
+					So(rc.Component.(*registeredReplaceableComponent).ID, ShouldEqual, "1")
+					So(sg.Connected("1"+REPLACEDSUFFIX, "1"), ShouldBeTrue)
+					So(sg.Validate(), ShouldBeNil)
+				}
+			})
+		})
+	})
+}
+
+func genericComponent(id string, values map[string]interface{}) *GenericComponent {
+	c := GenericComponent{
+		"_component_id": id,
+		"_component":    "instance",
+		"_provider":     "test",
+		"_action":       "",
+		"_state":        "",
+	}
+
+	for k, v := range values {
+		c[k] = v
+	}
+
+	return &c
+}
+
+func TestGenericComponent(t *testing.T) {
+	Convey("Given a generic component", t, func() {
+		c := genericComponent("instance::1", map[string]interface{}{
+			"_name":                    "web-1",
+			"_group":                   "web",
+			"_tags":                    map[string]interface{}{"env": "dev"},
+			"_dependencies":            []interface{}{"network::1"},
+			"_sequential_dependencies": []string{"network::1"},
+			"_provider_id":             "i-1234",
+			"_stateful":                false,
+			"size":                     1024,
+			"disks":                    []interface{}{"a", "b"},
+		})
+
+		Convey("It should return values stored on reserved keys", func() {
+			So(c.GetName(), ShouldEqual, "web-1")
+			So(c.GetGroup(), ShouldEqual, "web")
+			So(c.GetTags(), ShouldResemble, map[string]string{"env": "dev"})
+			So(c.GetTag("env"), ShouldEqual, "dev")
+			So(c.Dependencies(), ShouldResemble, []string{"network::1"})
+			So(c.SequentialDependencies(), ShouldResemble, []string{"network::1"})
+			So(c.GetProviderID(), ShouldEqual, "i-1234")
+			So(c.IsStateful(), ShouldBeFalse)
+		})
+
+		Convey("When diffing it against a previous version", func() {
+			oc := genericComponent("instance::1", map[string]interface{}{
+				"_name":  "web-0",
+				"_state": STATECOMPLETED,
+				"size":   float64(512),
+				"disks":  []interface{}{"a", "b"},
+				"old":    "value",
+			})
+			cl, err := c.Diff(oc)
+			Convey("It should return changes to non reserved values", func() {
+				So(err, ShouldBeNil)
+				So(len(cl), ShouldEqual, 2)
+				So(cl[0].Type, ShouldEqual, diff.DELETE)
+				So(cl[0].Path, ShouldResemble, []string{"old"})
+				So(cl[1].Type, ShouldEqual, diff.UPDATE)
+				So(cl[1].Path, ShouldResemble, []string{"size"})
+				So(cl[1].From, ShouldEqual, 512)
+				So(cl[1].To, ShouldEqual, 1024)
+			})
+		})
+
+		Convey("When diffing it against an identical component", func() {
+			cl, err := c.Diff(genericComponent("instance::1", map[string]interface{}{"size": 1024, "disks": []interface{}{"a", "b"}}))
+			Convey("It should return no changes", func() {
+				So(err, ShouldBeNil)
+				So(len(cl), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When updating it with provider returned values", func() {
+			c.SetState(STATERUNNING)
+			c.Update(genericComponent("instance::1", map[string]interface{}{"_provider_id": "i-5678", "ip": "10.0.0.1"}))
+			Convey("It should merge the values", func() {
+				So(c.GetProviderID(), ShouldEqual, "i-5678")
+				So((*c)["ip"], ShouldEqual, "10.0.0.1")
+				So((*c)["size"], ShouldEqual, 1024)
+				So(c.GetState(), ShouldEqual, STATERUNNING)
+			})
+		})
+	})
+
+	Convey("Given two graphs of generic components", t, func() {
+		og := New()
+		_ = og.AddComponent(genericComponent("network::1", map[string]interface{}{"cidr": "10.0.0.0/24"}))
+		_ = og.AddComponent(genericComponent("instance::1", map[string]interface{}{"_dependencies": []string{"network::1"}, "size": 512}))
+
+		ng := New()
+		_ = ng.AddComponent(genericComponent("network::1", map[string]interface{}{"cidr": "10.0.0.0/24"}))
+		_ = ng.AddComponent(genericComponent("instance::1", map[string]interface{}{"_dependencies": []string{"network::1"}, "size": 1024}))
+		_ = ng.AddComponent(genericComponent("instance::2", map[string]interface{}{"_dependencies": []string{"network::1"}, "size": 1024}))
+
+		Convey("When diffing the graphs with a changelog", func() {
+			g, err := ng.DiffWithChangelog(og)
+			Convey("It should return the correct changes and changelog", func() {
+				So(err, ShouldBeNil)
+				So(len(g.Changes), ShouldEqual, 2)
+				So(g.Changes[0].GetID(), ShouldEqual, "instance::1")
+				So(g.Changes[0].GetAction(), ShouldEqual, ACTIONUPDATE)
+				So(g.Changes[1].GetID(), ShouldEqual, "instance::2")
+				So(g.Changes[1].GetAction(), ShouldEqual, ACTIONCREATE)
+				So(g.Changelog[0].Path, ShouldResemble, []string{"instance::1", "size"})
+				So(g.Changelog[0].Type, ShouldEqual, diff.UPDATE)
+				So(g.Changelog[1].Path, ShouldResemble, []string{"instance::2", "_component"})
+				So(g.Changelog[1].Type, ShouldEqual, diff.CREATE)
+			})
+		})
+	})
+}
+
+func TestVisualisation(t *testing.T) {
+	Convey("Given a diffed graph of generic components", t, func() {
+		og := New()
+		_ = og.AddComponent(genericComponent("network::a", map[string]interface{}{"cidr": "10.0.0.0/24"}))
+		_ = og.AddComponent(genericComponent("network::b", map[string]interface{}{"cidr": "10.0.1.0/24"}))
+
+		ng := New()
+		_ = ng.AddComponent(genericComponent("network::a", map[string]interface{}{"cidr": "10.0.2.0/24"}))
+		_ = ng.AddComponent(genericComponent("instance::\"web\"", map[string]interface{}{"_dependencies": []string{"network::a"}}))
+
+		g, err := ng.Diff(og)
+		So(err, ShouldBeNil)
+
+		Convey("When exporting it as a mermaid flowchart", func() {
+			output := g.Mermaid()
+			Convey("It should return the correct flowchart", func() {
+				expected := []string{
+					"flowchart TD",
+					`  network__a["network::a<br/>instance<br/>update"]`,
+					`  instance___web_["instance::#quot;web#quot;<br/>instance<br/>create"]`,
+					`  network__b["network::b<br/>instance<br/>delete"]`,
+					`  start(["start"])`,
+					`  end_1(["end"])`,
+					"  network__a -->|create| instance___web_",
+					"  start -->|update| network__a",
+					"  instance___web_ --> end_1",
+					"  start -->|delete| network__b",
+					"  network__b --> end_1",
+					"  classDef create fill:#d4edda,stroke:#28a745",
+					"  classDef delete fill:#f8d7da,stroke:#dc3545",
+					"  classDef update fill:#fff3cd,stroke:#ffc107",
+					"  classDef none fill:#e2e3e5,stroke:#6c757d",
+					"  class network__a update",
+					"  class instance___web_ create",
+					"  class network__b delete",
+				}
+				So(output, ShouldEqual, strings.Join(expected, "\n"))
+			})
+		})
+
+		Convey("When exporting it as graphviz with options", func() {
+			(*g.Changes[0].(*GenericComponent))["_provider"] = "aws"
+			g.Changes[0].SetState(STATEERRORED)
+			output := g.GraphvizWithOptions(GraphvizOptions{Cluster: CLUSTERPROVIDER, Legend: true})
+			Convey("It should declare every node", func() {
+				So(output, ShouldStartWith, "digraph G {\n")
+				So(output, ShouldContainSubstring, `  subgraph "cluster_aws" {`+"\n"+`    label="aws"`+"\n"+`    "network::a" [label="network::a\ninstance", shape=cylinder, style="filled,bold,diagonals", fillcolor="#fff3cd", color="#ffc107", penwidth=2]`)
+				So(output, ShouldContainSubstring, `  subgraph "cluster_test" {`)
+				So(output, ShouldContainSubstring, `    "instance::\"web\"" [label="instance::\"web\"\ninstance", shape=cylinder, style="filled,dashed", fillcolor="#d4edda", color="#28a745"]`)
+				So(output, ShouldContainSubstring, `  "start" [shape=circle]`)
+				So(output, ShouldContainSubstring, `  "end" [shape=circle]`)
+			})
+			Convey("It should include all edges", func() {
+				So(output, ShouldContainSubstring, `  "network::a" -> "instance::\"web\"" [label="create"]`)
+				So(output, ShouldContainSubstring, `  "network::b" -> "end"`)
+			})
+			Convey("It should include a legend", func() {
+				So(output, ShouldContainSubstring, `  subgraph "cluster_legend" {`)
+				So(output, ShouldContainSubstring, `    "legend_action_delete" [label="delete", shape=box, style="filled", fillcolor="#f8d7da", color="#dc3545"]`)
+				So(output, ShouldEndWith, "  }\n}")
+			})
+		})
+	})
+}
+
+func benchmarkGraph(from, to, val int) *Graph {
+	g := New()
+	for i := from; i < to; i++ {
+		var deps []string
+		if i > from {
+			deps = append(deps, strconv.Itoa(from+(i-from)/2))
+		}
+		_ = g.AddComponent(&testComponent{Name: strconv.Itoa(i), Deps: deps, TestVal: val})
+	}
+	return g
+}
+
+// benchmarkDiff diffs two graphs sharing half of their components, resulting in creates and deletes
+func benchmarkDiff(b *testing.B, size int) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		ng := benchmarkGraph(0, size, 1)
+		og := benchmarkGraph(size/2, size+size/2, 1)
+		b.StartTimer()
+
+		_, err := ng.Diff(og)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkDiffUpdate diffs two graphs with the same components, resulting in every component being updated
+func benchmarkDiffUpdate(b *testing.B, size int) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		ng := benchmarkGraph(0, size, 2)
+		og := benchmarkGraph(0, size, 1)
+		b.StartTimer()
+
+		_, err := ng.Diff(og)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestFromDOT(t *testing.T) {
+	Convey("Given a DOT file", t, func() {
+		dot := `
+			// a web topology
+			digraph "web" {
+				node [provider=aws, action=create];
+				edge [length=2]
+
+				/* instances */
+				subgraph cluster_instances {
+					node [type=instance]
+					"instance::web" [action=update, size=2]
+					"instance::\"db\"" [state=completed]
+				}
+
+				start -> "network::a" -> { "instance::web" "instance::\"db\"" } -> end
+				"network::a" [type=network, stateful=false, cidr="10.0.0.0/16", label="ignored"]
+
+				"instance::web" -> "instance::\"db\"" [length=1]
+			}
+		`
+
+		Convey("When parsing it", func() {
+			g, err := FromDOT(strings.NewReader(dot))
+			So(err, ShouldBeNil)
+
+			Convey("It should create generic components for all nodes", func() {
+				So(g.Name, ShouldEqual, "web")
+				So(len(g.Components), ShouldEqual, 3)
+				So(g.HasComponent("start"), ShouldBeFalse)
+				So(g.HasComponent("end"), ShouldBeFalse)
+
+				network := g.Component("network::a")
+				So(network.GetType(), ShouldEqual, "network")
+				So(network.GetProvider(), ShouldEqual, "aws")
+				So(network.GetAction(), ShouldEqual, ACTIONCREATE)
+				So(network.IsStateful(), ShouldBeFalse)
+				So((*network.(*GenericComponent))["cidr"], ShouldEqual, "10.0.0.0/16")
+				So((*network.(*GenericComponent))["label"], ShouldBeNil)
+
+				web := g.Component("instance::web")
+				So(web.GetType(), ShouldEqual, "instance")
+				So(web.GetAction(), ShouldEqual, ACTIONUPDATE)
+				So((*web.(*GenericComponent))["size"], ShouldEqual, "2")
+
+				db := g.Component(`instance::"db"`)
+				So(db, ShouldNotBeNil)
+				So(db.GetType(), ShouldEqual, "instance")
+				So(db.GetAction(), ShouldEqual, ACTIONCREATE)
+				So(db.GetState(), ShouldEqual, STATECOMPLETED)
+			})
+
+			Convey("It should create edges for all edge chains", func() {
+				So(len(g.Edges), ShouldEqual, 6)
+				So(g.Connected("start", "network::a"), ShouldBeTrue)
+				So(g.Connected("network::a", "instance::web"), ShouldBeTrue)
+				So(g.Connected("network::a", `instance::"db"`), ShouldBeTrue)
+				So(g.Connected("instance::web", "end"), ShouldBeTrue)
+				So(g.Connected(`instance::"db"`, "end"), ShouldBeTrue)
+				So(g.LengthBetween("start", "network::a"), ShouldEqual, 1)
+				So(g.LengthBetween("instance::web", `instance::"db"`), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When parsing invalid files", func() {
+			_, err := FromDOT(strings.NewReader(`graph { a -- b }`))
+			So(err, ShouldNotBeNil)
+
+			_, err = FromDOT(strings.NewReader(`digraph { a -> }`))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Unexpected token in DOT file on line 1: }")
+
+			_, err = FromDOT(strings.NewReader(`digraph { a [action="create] }`))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func BenchmarkDiff(b *testing.B) {
+	for _, size := range []int{1000, 2000, 4000, 8000, 16000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			benchmarkDiff(b, size)
+		})
+	}
+}
+
+func BenchmarkDiffUpdate(b *testing.B) {
+	for _, size := range []int{1000, 2000, 4000, 8000, 16000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			benchmarkDiffUpdate(b, size)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// CLUSTERNONE : do not cluster nodes
+	CLUSTERNONE = ""
+	// CLUSTERPROVIDER : cluster nodes by their provider
+	CLUSTERPROVIDER = "provider"
+	// CLUSTERGROUP : cluster nodes by their group
+	CLUSTERGROUP = "group"
+)
+
+// colours holds the fill and stroke colour of a node
+type colours struct {
+	fill   string
+	stroke string
+}
+
+// actionColours maps actions to the colours used to render them
+var actionColours = map[string]colours{
+	ACTIONCREATE:  {fill: "#d4edda", stroke: "#28a745"},
+	ACTIONDELETE:  {fill: "#f8d7da", stroke: "#dc3545"},
+	ACTIONUPDATE:  {fill: "#fff3cd", stroke: "#ffc107"},
+	ACTIONREPLACE: {fill: "#fff3cd", stroke: "#ffc107"},
+	ACTIONNONE:    {fill: "#e2e3e5", stroke: "#6c757d"},
+	ACTIONFIND:    {fill: "#d1ecf1", stroke: "#17a2b8"},
+	ACTIONGET:     {fill: "#d1ecf1", stroke: "#17a2b8"},
+}
+
+// stateStyles maps states to the node style used to render them
+var stateStyles = map[string]string{
+	STATEWAITING:   "filled,dashed",
+	STATERUNNING:   "filled,bold",
+	STATECOMPLETED: "filled",
+	STATEERRORED:   "filled,bold,diagonals",
+	STATESKIPPED:   "filled,dotted",
+}
+
+var legendActions = []string{ACTIONCREATE, ACTIONUPDATE, ACTIONREPLACE, ACTIONDELETE, ACTIONFIND, ACTIONGET, ACTIONNONE}
+
+var legendStates = []string{STATEWAITING, STATERUNNING, STATECOMPLETED, STATEERRORED, STATESKIPPED}
+
+// GraphvizOptions configures the output of GraphvizWithOptions
+type GraphvizOptions struct {
+	Cluster string // clusters nodes by provider or group, see CLUSTERPROVIDER and CLUSTERGROUP
+	Legend  bool   // includes a legend of all actions and states
+}
+
+// GraphvizWithOptions outputs the graph in graphviz format, declaring every node with a shape
+// and colour based on its action and state. Stateful components are drawn as cylinders
+func (g *Graph) GraphvizWithOptions(opts GraphvizOptions) string {
+	var output []string
+	var clusters []string
+
+	ix := g.index()
+
+	declared := make(map[string]bool)
+	clustered := make(map[string][]string)
+
+	output = append(output, "digraph G {")
+
+	for _, c := range g.vertices() {
+		if declared[c.GetID()] {
+			continue
+		}
+		declared[c.GetID()] = true
+
+		node := dotNode(c)
+
+		var cluster string
+		switch opts.Cluster {
+		case CLUSTERPROVIDER:
+			cluster = c.GetProvider()
+		case CLUSTERGROUP:
+			cluster = c.GetGroup()
+		}
+
+		if cluster == "" {
+			output = append(output, "  "+node)
+			continue
+		}
+
+		if _, ok := clustered[cluster]; !ok {
+			clusters = append(clusters, cluster)
+		}
+		clustered[cluster] = append(clustered[cluster], node)
+	}
+
+	for _, cluster := range clusters {
+		output = append(output, fmt.Sprintf("  subgraph \"cluster_%s\" {", dotEscape(cluster)))
+		output = append(output, fmt.Sprintf("    label=\"%s\"", dotEscape(cluster)))
+		for _, node := range clustered[cluster] {
+			output = append(output, "    "+node)
+		}
+		output = append(output, "  }")
+	}
+
+	for _, edge := range g.Edges {
+		for _, id := range []string{edge.Source, edge.Destination} {
+			if declared[id] {
+				continue
+			}
+			declared[id] = true
+
+			c := ix.componentAll(id)
+			if c != nil {
+				output = append(output, "  "+dotNode(c))
+				continue
+			}
+
+			output = append(output, fmt.Sprintf("  \"%s\" [shape=circle]", dotEscape(id)))
+		}
+	}
+
+	for _, edge := range g.Edges {
+		dest := ix.componentAll(edge.Destination)
+		if dest != nil {
+			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\"]", dotEscape(edge.Source), dotEscape(edge.Destination), dotEscape(dest.GetAction())))
+		} else {
+			output = append(output, fmt.Sprintf("  \"%s\" -> \"%s\"", dotEscape(edge.Source), dotEscape(edge.Destination)))
+		}
+	}
+
+	if opts.Legend {
+		output = append(output, dotLegend()...)
+	}
+
+	output = append(output, "}")
+
+	return strings.Join(output, "\n")
+}
+
+// dotNode returns the node statement of a component
+func dotNode(c Component) string {
+	var attrs []string
+
+	label := dotEscape(c.GetID())
+	if c.GetType() != "" {
+		label = label + "\\n" + dotEscape(c.GetType())
+	}
+
+	attrs = append(attrs, fmt.Sprintf("label=\"%s\"", label))
+
+	if c.IsStateful() {
+		attrs = append(attrs, "shape=cylinder")
+	} else {
+		attrs = append(attrs, "shape=box")
+	}
+
+	style, ok := stateStyles[c.GetState()]
+	if !ok {
+		style = "filled"
+	}
+	attrs = append(attrs, fmt.Sprintf("style=\"%s\"", style))
+
+	if colour, ok := actionColours[c.GetAction()]; ok {
+		attrs = append(attrs, fmt.Sprintf("fillcolor=\"%s\"", colour.fill), fmt.Sprintf("color=\"%s\"", colour.stroke))
+	} else {
+		attrs = append(attrs, "fillcolor=\"white\"")
+	}
+
+	if c.GetState() == STATEERRORED {
+		attrs = append(attrs, "penwidth=2")
+	}
+
+	return fmt.Sprintf("\"%s\" [%s]", dotEscape(c.GetID()), strings.Join(attrs, ", "))
+}
+
+// dotLegend returns a cluster describing all actions and states
+func dotLegend() []string {
+	var output []string
+
+	output = append(output, "  subgraph \"cluster_legend\" {")
+	output = append(output, "    label=\"legend\"")
+
+	for _, action := range legendActions {
+		colour := actionColours[action]
+		output = append(output, fmt.Sprintf("    \"legend_action_%s\" [label=\"%s\", shape=box, style=\"filled\", fillcolor=\"%s\", color=\"%s\"]", action, action, colour.fill, colour.stroke))
+	}
+
+	for _, state := range legendStates {
+		output = append(output, fmt.Sprintf("    \"legend_state_%s\" [label=\"%s\", shape=box, style=\"%s\", fillcolor=\"white\"]", state, state, stateStyles[state]))
+	}
+
+	output = append(output, "    \"legend_stateful\" [label=\"stateful\", shape=cylinder]")
+	output = append(output, "  }")
+
+	return output
+}
+
+// dotEscape escapes quotes and backslashes in graphviz strings
+func dotEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return r.Replace(s)
+}
@@ -0,0 +1,83 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+// Impact is a component affected by a change, along with the length of the shortest
+// path from the changed components to it
+type Impact struct {
+	Component Component
+	Depth     int
+}
+
+// Descendants returns all components that can be reached from a component, ordered by distance
+func (g *Graph) Descendants(component string) *Neighbours {
+	ix := g.index()
+	return ix.transitive(ix.neighbours, component)
+}
+
+// Ancestors returns all components from which a component can be reached, ordered by distance
+func (g *Graph) Ancestors(component string) *Neighbours {
+	ix := g.index()
+	return ix.transitive(ix.origins, component)
+}
+
+// ImpactOf returns every component whose execution depends on any of the given components,
+// with the depth at which it is first reached. The given components are not included
+func (g *Graph) ImpactOf(components ...string) []Impact {
+	var impact []Impact
+
+	ix := g.index()
+
+	breadthFirst(ix.neighbours, components, func(id string, depth int) {
+		if c := ix.componentAll(id); c != nil {
+			impact = append(impact, Impact{Component: c, Depth: depth})
+		}
+	})
+
+	return impact
+}
+
+// transitive returns all components reachable from a component using the given adjacency
+func (ix *index) transitive(adjacency map[string][]string, component string) *Neighbours {
+	var n Neighbours
+
+	breadthFirst(adjacency, []string{component}, func(id string, depth int) {
+		if c := ix.componentAll(id); c != nil {
+			n = append(n, c)
+		}
+	})
+
+	return &n
+}
+
+// breadthFirst visits all vertices reachable from a set of vertices, excluding the set itself
+// and the start and end vertices
+func breadthFirst(adjacency map[string][]string, from []string, visit func(id string, depth int)) {
+	visited := make(map[string]bool)
+
+	for _, id := range from {
+		visited[id] = true
+	}
+
+	current := from
+
+	for depth := 1; len(current) > 0; depth++ {
+		var next []string
+
+		for _, v := range current {
+			for _, n := range adjacency[v] {
+				if visited[n] || isTerminal(n) {
+					continue
+				}
+				visited[n] = true
+
+				visit(n, depth)
+				next = append(next, n)
+			}
+		}
+
+		current = next
+	}
+}
@@ -0,0 +1,154 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "errors"
+
+// index provides constant time lookups of a graph's components and edges. An index is built from
+// the graph's current components, changes and edges by operations that perform many lookups, and is
+// kept up to date by the changes the operation makes through it. Indexes are never stored on the graph,
+// so a graph can be read from multiple goroutines and its fields can be changed directly
+type index struct {
+	graph       *Graph
+	byID        map[string]Component
+	changesByID map[string]Component
+	neighbours  map[string][]string
+	origins     map[string][]string
+	connected   map[Edge]bool
+}
+
+// index returns a new index of the graph's components, changes and edges
+func (g *Graph) index() *index {
+	ix := &index{
+		graph:       g,
+		byID:        mapComponents(g.Components),
+		changesByID: mapComponents(g.Changes),
+		neighbours:  make(map[string][]string),
+		origins:     make(map[string][]string),
+		connected:   make(map[Edge]bool),
+	}
+
+	for _, e := range g.Edges {
+		ix.indexEdge(e)
+	}
+
+	return ix
+}
+
+// component returns a component given the name matches
+func (ix *index) component(id string) Component {
+	return ix.byID[id]
+}
+
+// componentAll returns a component from either changes or components given the name matches
+func (ix *index) componentAll(id string) Component {
+	if c, ok := ix.changesByID[id]; ok {
+		return c
+	}
+	return ix.byID[id]
+}
+
+// addComponent adds a component to the graph's vertices if it does not already exist
+func (ix *index) addComponent(c Component) error {
+	if _, ok := ix.byID[c.GetID()]; ok {
+		return errors.New("Component already exists: " + c.GetID())
+	}
+
+	ix.graph.Components = append(ix.graph.Components, c)
+	ix.byID[c.GetID()] = c
+
+	return nil
+}
+
+// deleteComponent deletes a component from the graph
+func (ix *index) deleteComponent(c Component) {
+	ix.graph.DeleteComponent(c)
+	delete(ix.byID, c.GetID())
+}
+
+// connect adds an edge between two vertices if they are not already connected
+func (ix *index) connect(source, destination string) {
+	if !ix.connected[edgeKey(source, destination)] {
+		ix.addEdge(Edge{Source: source, Destination: destination, Length: 1})
+	}
+}
+
+// addEdge appends an edge to the graph
+func (ix *index) addEdge(e Edge) {
+	ix.graph.Edges = append(ix.graph.Edges, e)
+	ix.indexEdge(e)
+}
+
+// removeEdge removes the edge at the given position
+func (ix *index) removeEdge(i int) {
+	e := ix.graph.Edges[i]
+	ix.graph.Edges = append(ix.graph.Edges[:i], ix.graph.Edges[i+1:]...)
+
+	ix.neighbours[e.Source] = removeID(ix.neighbours[e.Source], e.Destination)
+	ix.origins[e.Destination] = removeID(ix.origins[e.Destination], e.Source)
+	ix.connected[edgeKey(e.Source, e.Destination)] = containsID(ix.neighbours[e.Source], e.Destination)
+}
+
+// neighbourComponents returns all dependencies of a component
+func (ix *index) neighbourComponents(id string) *Neighbours {
+	var n Neighbours
+
+	for _, destination := range ix.neighbours[id] {
+		n = append(n, ix.byID[destination])
+	}
+
+	return n.Unique()
+}
+
+// originComponents returns all source components of a component
+func (ix *index) originComponents(id string) *Neighbours {
+	var n Neighbours
+
+	for _, source := range ix.origins[id] {
+		n = append(n, ix.byID[source])
+	}
+
+	return n.Unique()
+}
+
+func (ix *index) indexEdge(e Edge) {
+	ix.neighbours[e.Source] = append(ix.neighbours[e.Source], e.Destination)
+	ix.origins[e.Destination] = append(ix.origins[e.Destination], e.Source)
+	ix.connected[edgeKey(e.Source, e.Destination)] = true
+}
+
+func edgeKey(source, destination string) Edge {
+	return Edge{Source: source, Destination: destination}
+}
+
+func mapComponents(components []Component) map[string]Component {
+	m := make(map[string]Component, len(components))
+
+	for _, c := range components {
+		if _, ok := m[c.GetID()]; !ok {
+			m[c.GetID()] = c
+		}
+	}
+
+	return m
+}
+
+func removeID(ids []string, id string) []string {
+	for i := range ids {
+		if ids[i] == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
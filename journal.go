@@ -0,0 +1,210 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	journalGraph  = "graph"
+	journalState  = "state"
+	journalAction = "action"
+)
+
+type journalEntry struct {
+	Type        string          `json:"type"`
+	Time        time.Time       `json:"time"`
+	ComponentID string          `json:"component_id,omitempty"`
+	Value       string          `json:"value,omitempty"`
+	Graph       json.RawMessage `json:"graph,omitempty"`
+}
+
+// Journal records the state and action transitions of a graph's components to an append-only file,
+// one json entry per line. The first entry of a journal is a snapshot of the graph, so the graph's
+// progress can be recovered with Resume if the process applying it dies
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// CreateJournal creates a new journal file, starting with a snapshot of the graph. It fails if the file already exists
+func CreateJournal(path string, g *Graph) (*Journal, error) {
+	data, err := g.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{file: f}
+
+	err = j.write(journalEntry{Type: journalGraph, Graph: data})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// OpenJournal opens an existing journal file, so that further transitions are appended to it
+func OpenJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{file: f}, nil
+}
+
+// SetState sets the state of a component and records the transition
+func (j *Journal) SetState(c Component, state string) error {
+	c.SetState(state)
+	return j.write(journalEntry{Type: journalState, ComponentID: c.GetID(), Value: state})
+}
+
+// SetAction sets the action of a component and records the transition
+func (j *Journal) SetAction(c Component, action string) error {
+	c.SetAction(action)
+	return j.write(journalEntry{Type: journalAction, ComponentID: c.GetID(), Value: action})
+}
+
+// Close closes the journal's file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// write appends an entry to the journal, syncing it to disk before returning
+func (j *Journal) write(e journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.Time = time.Now().UTC()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+// Resume loads the graph from a journal and replays all recorded transitions. It returns a new plan
+// containing only the changes that have not completed, with completed changes collapsed out of its
+// edges, along with all changes that were running, as they may need to be reconciled before being
+// processed again. A partially written last entry is ignored
+func (g *Graph) Resume(r io.Reader) (*Graph, ComponentGroup, error) {
+	var running ComponentGroup
+
+	// the index of the graph, once it has been loaded from the journal
+	var ix *index
+
+	br := bufio.NewReader(r)
+
+	for {
+		line, rerr := br.ReadBytes('\n')
+		if rerr != nil && rerr != io.EOF {
+			return nil, nil, rerr
+		}
+
+		if len(line) > 0 {
+			var e journalEntry
+
+			err := json.Unmarshal(line, &e)
+			if err != nil {
+				// the last entry may have been partially written
+				if rerr == io.EOF {
+					break
+				}
+				return nil, nil, err
+			}
+
+			err = g.replay(e, ix)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if ix == nil {
+				ix = g.index()
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+	}
+
+	if ix == nil {
+		return nil, nil, errors.New("Journal does not contain a graph")
+	}
+
+	pending := make(map[string]bool)
+
+	for _, c := range g.Changes {
+		if c.GetState() == STATECOMPLETED {
+			continue
+		}
+
+		pending[c.GetID()] = true
+
+		if c.GetState() == STATERUNNING {
+			running = append(running, c)
+		}
+	}
+
+	plan := g.CollapsedSubgraph(func(c Component) bool {
+		return pending[c.GetID()]
+	})
+
+	plan.Components = g.Components
+	plan.Changelog = g.Changelog
+
+	return plan, running, nil
+}
+
+// replay applies a journal entry to the graph
+func (g *Graph) replay(e journalEntry, ix *index) error {
+	if ix == nil {
+		if e.Type != journalGraph {
+			return errors.New("Journal does not start with a graph")
+		}
+		return json.Unmarshal(e.Graph, g)
+	}
+
+	if e.Type != journalState && e.Type != journalAction {
+		return errors.New("Unknown journal entry: " + e.Type)
+	}
+
+	c := ix.componentAll(e.ComponentID)
+	if c == nil {
+		return errors.New("Journal references a component that does not exist: " + e.ComponentID)
+	}
+
+	if e.Type == journalState {
+		c.SetState(e.Value)
+	} else {
+		c.SetAction(e.Value)
+	}
+
+	return nil
+}
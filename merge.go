@@ -0,0 +1,175 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "errors"
+
+const (
+	// MERGEERROR : fail the merge if two graphs contain the same component
+	MERGEERROR = "error"
+	// MERGELEFT : keep the component of the graph that was merged first
+	MERGELEFT = "left"
+	// MERGERIGHT : keep the component of the graph that was merged last
+	MERGERIGHT = "right"
+	// MERGEUPDATE : update the component of the graph that was merged first with the values of the other
+	MERGEUPDATE = "update"
+)
+
+// Conflict describes a component that exists in more than one of the merged graphs
+type Conflict struct {
+	ComponentID string
+	Left        Component // the component merged first
+	Right       Component // the component that conflicts with it
+	Resolved    Component // the component that was kept
+}
+
+// Resolver resolves a component that exists in more than one merged graph, returning the component to keep.
+// Left is the component merged first, while right is the component that conflicts with it
+type Resolver func(left, right Component) (Component, error)
+
+// resolvers holds the built in resolver of every merge policy, other than MERGEERROR
+var resolvers = map[string]Resolver{
+	MERGELEFT:   ResolveLeft,
+	MERGERIGHT:  ResolveRight,
+	MERGEUPDATE: ResolveUpdate,
+}
+
+// ResolveLeft keeps the component of the graph that was merged first
+func ResolveLeft(left, right Component) (Component, error) {
+	return left, nil
+}
+
+// ResolveRight keeps the component of the graph that was merged last
+func ResolveRight(left, right Component) (Component, error) {
+	return right, nil
+}
+
+// ResolveUpdate updates a copy of the component of the graph that was merged first with the values of the other
+func ResolveUpdate(left, right Component) (Component, error) {
+	c, err := CloneComponent(left)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Update(right)
+
+	return c, nil
+}
+
+// Merge combines multiple graphs into a new graph, failing if any component exists in more than one graph
+func Merge(graphs ...*Graph) (*Graph, []Conflict, error) {
+	return MergeWithPolicy(MERGEERROR, graphs...)
+}
+
+// MergeWithPolicy combines multiple graphs into a new graph, resolving components that exist in more than
+// one graph with the given policy. The new graph contains the union of all components, changes and edges,
+// with start and end recalculated. All conflicts are reported, regardless of the policy used.
+// Merged components are not modified, as the MERGEUPDATE policy updates a copy of the left component
+func MergeWithPolicy(policy string, graphs ...*Graph) (*Graph, []Conflict, error) {
+	if policy == MERGEERROR {
+		return MergeWithResolver(nil, graphs...)
+	}
+
+	resolve, ok := resolvers[policy]
+	if !ok {
+		return nil, nil, errors.New("Unknown merge policy: " + policy)
+	}
+
+	return MergeWithResolver(resolve, graphs...)
+}
+
+// MergeWithResolver combines multiple graphs into a new graph in the same way as MergeWithPolicy, resolving
+// components that exist in more than one graph by calling a resolver. If the resolver is nil, or returns an
+// error for any component, the merge fails and the errors are returned as a MultiError
+func MergeWithResolver(resolve Resolver, graphs ...*Graph) (*Graph, []Conflict, error) {
+	var conflicts []Conflict
+	var errs []error
+
+	ng := New()
+	if len(graphs) > 0 {
+		ng = graphs[0].emptyCopy()
+	}
+
+	for _, g := range graphs {
+		merged, c, err := mergeComponents(resolve, ng.Components, g.Components)
+		ng.Components = merged
+		conflicts = append(conflicts, c...)
+		errs = append(errs, err...)
+
+		merged, c, err = mergeComponents(resolve, ng.Changes, g.Changes)
+		ng.Changes = merged
+		conflicts = append(conflicts, c...)
+		errs = append(errs, err...)
+	}
+
+	if len(errs) > 0 {
+		return nil, conflicts, &MultiError{Errors: errs}
+	}
+
+	nix := ng.index()
+
+	for _, g := range graphs {
+		for _, e := range g.Edges {
+			if isTerminal(e.Source) || isTerminal(e.Destination) || nix.connected[edgeKey(e.Source, e.Destination)] {
+				continue
+			}
+			nix.addEdge(e)
+		}
+	}
+
+	if len(ng.Changes) > 0 {
+		components := ng.Components
+		ng.Components = ng.Changes
+		ng.SetStartFinish()
+		ng.Components = components
+	} else {
+		ng.SetStartFinish()
+	}
+
+	return ng, conflicts, nil
+}
+
+// mergeComponents adds components to a collection, resolving any conflicts with the given resolver
+func mergeComponents(resolve Resolver, left, right []Component) ([]Component, []Conflict, []error) {
+	var conflicts []Conflict
+	var errs []error
+
+	position := make(map[string]int)
+	for i, c := range left {
+		position[c.GetID()] = i
+	}
+
+	for _, rc := range right {
+		i, ok := position[rc.GetID()]
+		if !ok {
+			position[rc.GetID()] = len(left)
+			left = append(left, rc)
+			continue
+		}
+
+		lc := left[i]
+
+		conflicts = append(conflicts, Conflict{ComponentID: rc.GetID(), Left: lc, Right: rc, Resolved: lc})
+
+		if resolve == nil {
+			errs = append(errs, errors.New("Component already exists: "+rc.GetID()))
+			continue
+		}
+
+		c, err := resolve(lc, rc)
+		if err == nil && c == nil {
+			err = errors.New("Conflict was not resolved")
+		}
+		if err != nil {
+			errs = append(errs, &ComponentError{ComponentID: rc.GetID(), Err: err})
+			continue
+		}
+
+		left[i] = c
+		conflicts[len(conflicts)-1].Resolved = c
+	}
+
+	return left, conflicts, errs
+}
@@ -0,0 +1,126 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// actionClasses maps actions to the mermaid class used to style them
+var actionClasses = map[string]string{
+	ACTIONCREATE:  "create",
+	ACTIONDELETE:  "delete",
+	ACTIONUPDATE:  "update",
+	ACTIONREPLACE: "update",
+	ACTIONNONE:    "none",
+}
+
+// mermaidKeywords can not be used as node id's
+var mermaidKeywords = map[string]bool{
+	"end":       true,
+	"graph":     true,
+	"flowchart": true,
+	"subgraph":  true,
+	"direction": true,
+	"class":     true,
+	"classDef":  true,
+	"click":     true,
+	"style":     true,
+	"linkStyle": true,
+}
+
+var mermaidInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Mermaid outputs the graph as a mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var output []string
+	var classes []string
+
+	ix := g.index()
+
+	nodes := make(map[string]string)
+	used := make(map[string]bool)
+	members := make(map[string][]string)
+
+	output = append(output, "flowchart TD")
+
+	declare := func(id string) string {
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+
+		base := mermaidInvalidChars.ReplaceAllString(id, "_")
+
+		n := base
+		for i := 1; n == "" || used[n] || mermaidKeywords[n]; i++ {
+			n = fmt.Sprintf("%s_%d", base, i)
+		}
+
+		nodes[id] = n
+		used[n] = true
+
+		c := ix.componentAll(id)
+		if c == nil {
+			output = append(output, fmt.Sprintf("  %s([\"%s\"])", n, mermaidEscape(id)))
+			return n
+		}
+
+		label := []string{mermaidEscape(id), mermaidEscape(c.GetType())}
+		if c.GetAction() != "" {
+			label = append(label, mermaidEscape(c.GetAction()))
+		}
+
+		output = append(output, fmt.Sprintf("  %s[\"%s\"]", n, strings.Join(label, "<br/>")))
+
+		class, ok := actionClasses[c.GetAction()]
+		if ok {
+			if _, exists := members[class]; !exists {
+				classes = append(classes, class)
+			}
+			members[class] = append(members[class], n)
+		}
+
+		return n
+	}
+
+	for _, c := range g.vertices() {
+		declare(c.GetID())
+	}
+
+	var edges []string
+
+	for _, edge := range g.Edges {
+		source := declare(edge.Source)
+		destination := declare(edge.Destination)
+
+		dest := ix.componentAll(edge.Destination)
+		if dest != nil && dest.GetAction() != "" {
+			edges = append(edges, fmt.Sprintf("  %s -->|%s| %s", source, mermaidEscape(dest.GetAction()), destination))
+		} else {
+			edges = append(edges, fmt.Sprintf("  %s --> %s", source, destination))
+		}
+	}
+
+	output = append(output, edges...)
+
+	for _, action := range []string{ACTIONCREATE, ACTIONDELETE, ACTIONUPDATE, ACTIONNONE} {
+		colour := actionColours[action]
+		output = append(output, fmt.Sprintf("  classDef %s fill:%s,stroke:%s", actionClasses[action], colour.fill, colour.stroke))
+	}
+
+	for _, class := range classes {
+		output = append(output, fmt.Sprintf("  class %s %s", strings.Join(members[class], ","), class))
+	}
+
+	return strings.Join(output, "\n")
+}
+
+// mermaidEscape escapes characters that can not be used in mermaid labels
+func mermaidEscape(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;")
+	return r.Replace(s)
+}
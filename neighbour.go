@@ -21,13 +21,14 @@ func (n *Neighbours) Exists(component string) bool {
 func (n *Neighbours) Unique() *Neighbours {
 	var un Neighbours
 
+	seen := make(map[string]bool)
+
 	for _, v := range *n {
-		if v == nil {
+		if v == nil || seen[v.GetID()] {
 			continue
 		}
-		if !un.Exists(v.GetID()) {
-			un = append(un, v)
-		}
+		seen[v.GetID()] = true
+		un = append(un, v)
 	}
 
 	return &un
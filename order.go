@@ -0,0 +1,89 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "sort"
+
+// TopologicalSort returns the graph's changes in an order that satisfies all edges.
+// Changes that do not depend on each other keep the order they have in Changes.
+func (g *Graph) TopologicalSort() ([]Component, error) {
+	var sorted []Component
+
+	waves, err := g.Waves()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range waves {
+		sorted = append(sorted, w...)
+	}
+
+	return sorted, nil
+}
+
+// Waves returns the graph's changes grouped into levels. Every change in a wave only
+// depends on changes from previous waves, so all changes in a wave can be processed in parallel.
+func (g *Graph) Waves() ([][]Component, error) {
+	var waves [][]Component
+
+	position := make(map[string]int)
+	for i, c := range g.Changes {
+		position[c.GetID()] = i
+	}
+
+	indegree := make(map[string]int)
+	neighbours := make(map[string][]string)
+	seen := make(map[Edge]bool)
+
+	for _, e := range g.Edges {
+		_, hasSource := position[e.Source]
+		_, hasDestination := position[e.Destination]
+
+		key := Edge{Source: e.Source, Destination: e.Destination}
+		if !hasSource || !hasDestination || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		neighbours[e.Source] = append(neighbours[e.Source], e.Destination)
+		indegree[e.Destination]++
+	}
+
+	var current []Component
+	for _, c := range g.Changes {
+		if indegree[c.GetID()] == 0 {
+			current = append(current, c)
+		}
+	}
+
+	processed := 0
+
+	for len(current) > 0 {
+		var next []Component
+
+		for _, c := range current {
+			for _, n := range neighbours[c.GetID()] {
+				indegree[n]--
+				if indegree[n] == 0 {
+					next = append(next, g.Changes[position[n]])
+				}
+			}
+		}
+
+		sort.SliceStable(next, func(i, j int) bool {
+			return position[next[i].GetID()] < position[next[j].GetID()]
+		})
+
+		waves = append(waves, current)
+		processed = processed + len(current)
+		current = next
+	}
+
+	if processed < len(g.Changes) {
+		return nil, &CycleError{Cycle: g.firstCycle()}
+	}
+
+	return waves, nil
+}
@@ -0,0 +1,422 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/r3labs/diff"
+)
+
+// PatchResult reports the outcome of applying a single change
+type PatchResult struct {
+	Change  diff.Change
+	Applied bool
+	Err     error
+}
+
+// Patch applies a changelog, as produced by DiffWithChangelog, to the graph's components. Every change is routed
+// to the component named by the first element of its path. Components that do not exist are created from their
+// created values, using the type registered for their '_provider' and '_component'. Components are deleted if the
+// deletion of their '_component_id' is included, while all other changes update the component's values. Changes are
+// not applied if the value they change from does not match the component's current value. Edges are not rebuilt,
+// other than removing the edges of deleted components. A result is returned for every change, in the order of the changelog
+func (g *Graph) Patch(cl diff.Changelog) []PatchResult {
+	var order []string
+
+	results := make([]PatchResult, len(cl))
+	grouped := make(map[string][]int)
+
+	for i, change := range cl {
+		results[i].Change = change
+
+		if len(change.Path) < 2 {
+			results[i].Err = errors.New("Change does not reference a component value: " + strings.Join(change.Path, "."))
+			continue
+		}
+
+		id := change.Path[0]
+		if _, ok := grouped[id]; !ok {
+			order = append(order, id)
+		}
+		grouped[id] = append(grouped[id], i)
+	}
+
+	ix := g.index()
+
+	for _, id := range order {
+		c := ix.component(id)
+
+		switch {
+		case c == nil:
+			ix.patchCreate(id, grouped[id], results)
+		case isDeletion(grouped[id], results):
+			ix.patchDelete(c, grouped[id], results)
+		default:
+			patchUpdate(c, grouped[id], results)
+		}
+	}
+
+	return results
+}
+
+// patchCreate creates a component from all of its created values
+func (ix *index) patchCreate(id string, changes []int, results []PatchResult) {
+	for _, i := range changes {
+		if results[i].Change.Type != diff.CREATE {
+			report(changes, results, errors.New("Component does not exist: "+id))
+			return
+		}
+	}
+
+	c, err := createdComponent(id, changes, results)
+	if err == nil && c.GetID() != id {
+		err = errors.New("Created component does not match id: " + id)
+	}
+	if err == nil {
+		err = ix.addComponent(c)
+	}
+
+	report(changes, results, err)
+}
+
+// createdComponent loads a component from its created values, using the type registered for its
+// '_provider' and '_component'. The values of struct components are named by their diff names,
+// so they are converted to json names once the component's type is known
+func createdComponent(id string, changes []int, results []PatchResult) (Component, error) {
+	m, err := createdValues(nil, changes, results)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, hasProvider := m["_provider"].(string)
+	ctype, hasType := m["_component"].(string)
+
+	if !hasProvider || !hasType {
+		return nil, errors.New("Could not find the type of created component: " + id)
+	}
+
+	if c := NewComponent(provider, ctype); c != nil {
+		m, err = createdValues(reflect.TypeOf(c), changes, results)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return LoadComponent(m)
+}
+
+// createdValues returns the created values of a component, converting their paths to json names for the given type
+func createdValues(t reflect.Type, changes []int, results []PatchResult) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	for _, i := range changes {
+		path := results[i].Change.Path[1:]
+		if t != nil {
+			path = jsonPath(t, path)
+		}
+
+		v, err := patchValue(m, path, results[i].Change)
+		if err != nil {
+			return nil, err
+		}
+		m = v.(map[string]interface{})
+	}
+
+	return m, nil
+}
+
+// patchDelete deletes a component and all of its edges
+func (ix *index) patchDelete(c Component, changes []int, results []PatchResult) {
+	for i := len(ix.graph.Edges) - 1; i >= 0; i-- {
+		if ix.graph.Edges[i].Source == c.GetID() || ix.graph.Edges[i].Destination == c.GetID() {
+			ix.removeEdge(i)
+		}
+	}
+
+	ix.deleteComponent(c)
+
+	report(changes, results, nil)
+}
+
+// patchUpdate applies every change to the component's values
+func patchUpdate(c Component, changes []int, results []PatchResult) {
+	var m map[string]interface{}
+
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		report(changes, results, err)
+		return
+	}
+
+	for _, i := range changes {
+		path := jsonPath(reflect.TypeOf(c), results[i].Change.Path[1:])
+
+		v, err := patchValue(m, path, results[i].Change)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		m = v.(map[string]interface{})
+		results[i].Applied = true
+	}
+
+	err = setValues(c, m)
+	if err != nil {
+		report(changes, results, err)
+	}
+}
+
+// isDeletion returns true if the changes to a component delete its '_component_id'
+func isDeletion(changes []int, results []PatchResult) bool {
+	for _, i := range changes {
+		change := results[i].Change
+
+		if change.Type == diff.DELETE && len(change.Path) == 2 && change.Path[1] == "_component_id" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// patchValue applies a change to the value at a path within a value, returning the patched value.
+// Updated and deleted values must match the value the change is from
+func patchValue(v interface{}, path []string, change diff.Change) (interface{}, error) {
+	if len(path) < 1 {
+		err := checkFrom(v, change)
+		if err != nil {
+			return nil, err
+		}
+
+		if change.Type == diff.DELETE {
+			return nil, nil
+		}
+		return jsonValue(change.To)
+	}
+
+	if v == nil && change.Type == diff.CREATE {
+		v = make(map[string]interface{})
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 && change.Type == diff.DELETE {
+			err := checkFrom(x[path[0]], change)
+			if err != nil {
+				return nil, err
+			}
+
+			delete(x, path[0])
+			return x, nil
+		}
+
+		nv, err := patchValue(x[path[0]], path[1:], change)
+		if err != nil {
+			return nil, err
+		}
+
+		x[path[0]] = nv
+
+		return x, nil
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 {
+			return nil, errors.New("Could not patch slice element: " + path[0])
+		}
+
+		if len(path) == 1 {
+			return patchSlice(x, i, change)
+		}
+
+		if i >= len(x) {
+			return nil, errors.New("Could not find slice element: " + path[0])
+		}
+
+		x[i], err = patchValue(x[i], path[1:], change)
+
+		return x, err
+	}
+
+	return nil, errors.New("Could not patch value: " + path[0])
+}
+
+// patchSlice applies a change to an element of a slice. Deleted and updated elements are found by
+// their previous value, as positions change as elements are removed
+func patchSlice(s []interface{}, i int, change diff.Change) ([]interface{}, error) {
+	if change.Type == diff.CREATE {
+		to, err := jsonValue(change.To)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > len(s) {
+			i = len(s)
+		}
+
+		return append(s[:i], append([]interface{}{to}, s[i:]...)...), nil
+	}
+
+	from, err := jsonValue(change.From)
+	if err != nil {
+		return nil, err
+	}
+
+	i = -1
+	for j := range s {
+		if reflect.DeepEqual(s[j], from) {
+			i = j
+			break
+		}
+	}
+
+	if i < 0 {
+		return nil, &ConflictError{Path: change.Path, From: change.From}
+	}
+
+	if change.Type == diff.DELETE {
+		return append(s[:i], s[i+1:]...), nil
+	}
+
+	s[i], err = jsonValue(change.To)
+
+	return s, err
+}
+
+// setValues replaces all values of a component
+func setValues(c Component, m map[string]interface{}) error {
+	if gc, ok := c.(*GenericComponent); ok {
+		for k := range *gc {
+			delete(*gc, k)
+		}
+		for k, v := range m {
+			(*gc)[k] = v
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("Could not patch component: " + c.GetID())
+	}
+
+	nv := reflect.New(v.Elem().Type())
+
+	err = json.Unmarshal(data, nv.Interface())
+	if err != nil {
+		return err
+	}
+
+	v.Elem().Set(nv.Elem())
+
+	return nil
+}
+
+// jsonPath converts a path of diff names, as used in a changelog, to the json names of a type's values.
+// Embedded structs are part of the diff path, while their fields are promoted in json
+func jsonPath(t reflect.Type, path []string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if len(path) < 1 {
+		return path
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		f, ok := diffField(t, path[0])
+		if !ok {
+			return path
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		if f.Anonymous && name == "" {
+			return jsonPath(f.Type, path[1:])
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		return append([]string{name}, jsonPath(f.Type, path[1:])...)
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return append([]string{path[0]}, jsonPath(t.Elem(), path[1:])...)
+	}
+
+	return path
+}
+
+// diffField returns the field of a struct with the given diff name
+func diffField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		n := strings.Split(f.Tag.Get("diff"), ",")[0]
+		if n == "" {
+			n = f.Name
+		}
+
+		if n == name {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// jsonValue converts a value to its json equivalent
+func jsonValue(v interface{}) (interface{}, error) {
+	var jv interface{}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &jv)
+
+	return jv, err
+}
+
+// checkFrom returns an error if an updated or deleted value does not match the value the change is from
+func checkFrom(v interface{}, change diff.Change) error {
+	if change.Type == diff.CREATE {
+		return nil
+	}
+
+	from, err := jsonValue(change.From)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(v, from) {
+		return &ConflictError{Path: change.Path, From: change.From, Value: v}
+	}
+
+	return nil
+}
+
+// report sets the result of all changes to a component
+func report(changes []int, results []PatchResult, err error) {
+	for _, i := range changes {
+		results[i].Applied = err == nil
+		results[i].Err = err
+	}
+}
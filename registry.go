@@ -0,0 +1,119 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Factory returns a new, empty component
+type Factory func() Component
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+// Register registers a factory for components of a given provider and type.
+// When loading a graph, components with matching '_provider' and '_component' values
+// will be created by the factory, instead of being loaded as a GenericComponent
+func Register(provider, ctype string, f Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.factories[provider+"/"+ctype] = f
+}
+
+// NewComponent creates a component using the factory registered for a provider and type.
+// A nil component is returned if no factory is registered
+func NewComponent(provider, ctype string) Component {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	f, ok := registry.factories[provider+"/"+ctype]
+	if !ok {
+		return nil
+	}
+
+	return f()
+}
+
+// LoadComponent creates a component from its map representation. The component's type is determined
+// by its '_provider' and '_component' values, falling back to a GenericComponent for unregistered types.
+// The previous version of a replaced component, identified by its '_replaces' value, is loaded as a ReplacedComponent
+func LoadComponent(m map[string]interface{}) (Component, error) {
+	if id, ok := m["_replaces"].(string); ok && id != "" {
+		return loadReplaced(id, m)
+	}
+
+	provider, _ := m["_provider"].(string)
+	ctype, _ := m["_component"].(string)
+
+	c := NewComponent(provider, ctype)
+	if c == nil {
+		return MapGenericComponent(m), nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, json.Unmarshal(data, c)
+}
+
+// unmarshalComponent creates a component from its json representation
+func unmarshalComponent(data []byte) (Component, error) {
+	var t struct {
+		Provider string `json:"_provider"`
+		Type     string `json:"_component"`
+		Replaces string `json:"_replaces"`
+	}
+
+	err := json.Unmarshal(data, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Replaces != "" {
+		var m map[string]interface{}
+
+		err = json.Unmarshal(data, &m)
+		if err != nil {
+			return nil, err
+		}
+
+		return loadReplaced(t.Replaces, m)
+	}
+
+	c := NewComponent(t.Provider, t.Type)
+	if c == nil {
+		gc := make(GenericComponent)
+		c = &gc
+	}
+
+	return c, json.Unmarshal(data, c)
+}
+
+// loadReplaced creates the previous version of a replaced component, restoring the '_component_id' of the component
+func loadReplaced(id string, m map[string]interface{}) (Component, error) {
+	values := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		if k != "_replaces" {
+			values[k] = v
+		}
+	}
+
+	values["_component_id"] = id
+
+	c, err := LoadComponent(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplacedComponent{Component: c}, nil
+}
@@ -0,0 +1,96 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"encoding/json"
+
+	"github.com/r3labs/diff"
+)
+
+// REPLACEDSUFFIX : suffix added to the id of the previous version of a replaced component
+const REPLACEDSUFFIX = "::replaced"
+
+// ReplacedComponent is the previous version of a component that is being replaced.
+// It is deleted as a separate step of the replacement, so is identified by the
+// component's id followed by REPLACEDSUFFIX
+type ReplacedComponent struct {
+	Component
+}
+
+// GetID : returns the component's ID
+func (rc *ReplacedComponent) GetID() string {
+	return rc.Component.GetID() + REPLACEDSUFFIX
+}
+
+// MarshalJSON serialises the previous version of the component, replacing its '_component_id'. The id of the
+// component being replaced is kept as '_replaces', so it can be loaded as a ReplacedComponent again
+func (rc *ReplacedComponent) MarshalJSON() ([]byte, error) {
+	var m map[string]interface{}
+
+	data, err := json.Marshal(rc.Component)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	m["_component_id"] = rc.GetID()
+	m["_replaces"] = rc.Component.GetID()
+
+	return json.Marshal(m)
+}
+
+// requiresReplacement returns true if a component can only apply its changes by being replaced
+func requiresReplacement(c Component, changes diff.Changelog) bool {
+	r, ok := c.(Replaceable)
+	if !ok {
+		return false
+	}
+	return r.RequiresReplacement(changes)
+}
+
+// createBeforeDestroy returns true if the new version of a replaced component should be created first
+func createBeforeDestroy(c Component) bool {
+	cbd, ok := c.(CreateBeforeDestroyer)
+	if !ok {
+		return false
+	}
+	return cbd.CreateBeforeDestroy()
+}
+
+// connectReplacements orders the create and delete steps of all replaced components
+func (ix *index) connectReplacements() {
+	for _, c := range ix.graph.Components {
+		rc, ok := c.(*ReplacedComponent)
+		if !ok {
+			continue
+		}
+
+		id := rc.Component.GetID()
+		cbd := createBeforeDestroy(ix.component(id))
+
+		if cbd {
+			ix.connect(id, rc.GetID())
+		} else {
+			ix.connect(rc.GetID(), id)
+		}
+
+		// dependents need to be deleted before the previous version is deleted. When creating
+		// the new version first, all dependents are processed before the previous version is deleted
+		for _, d := range ix.graph.Components {
+			if d == c || !containsID(d.Dependencies(), id) {
+				continue
+			}
+
+			if cbd || d.GetAction() == ACTIONDELETE {
+				ix.connect(d.GetID(), rc.GetID())
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "errors"
+
+// RollbackPlan returns a new graph that reverts all completed changes of a diffed graph.
+// Created components are deleted, updated components are restored to their previous version
+// and deleted stateful components are created again. A completed replacement is reverted by
+// deleting the replacing version, identified by REPLACEDSUFFIX, and creating the previous
+// version under its own id. Edges are reversed, so changes are reverted in the opposite order
+// to which they were applied
+func (g *Graph) RollbackPlan() (*Graph, error) {
+	ng := g.emptyCopy()
+
+	ix := g.index()
+	nix := ng.index()
+
+	// steps holds the id of the rollback step reverting each change, reverts the change reverted by each step
+	steps := make(map[string]string)
+	reverts := make(map[string]string)
+
+	for _, c := range g.Changes {
+		if c.GetState() != STATECOMPLETED {
+			continue
+		}
+
+		var source Component
+		var action string
+		var replaced bool
+
+		switch c.GetAction() {
+		case ACTIONCREATE:
+			source, action = c, ACTIONDELETE
+		case ACTIONREPLACE:
+			// the previous version may be created again with the same id, so the replacing version
+			// is deleted as the replaced component
+			source, action, replaced = c, ACTIONDELETE, true
+		case ACTIONUPDATE:
+			oc := ix.component(c.GetID())
+			if oc == nil {
+				return nil, errors.New("Could not find previous version of component: " + c.GetID())
+			}
+			source, action = oc, ACTIONUPDATE
+		case ACTIONDELETE:
+			if r, ok := c.(*ReplacedComponent); ok {
+				source, action = r.Component, ACTIONCREATE
+				break
+			}
+			if !c.IsStateful() {
+				continue
+			}
+			source, action = c, ACTIONCREATE
+		default:
+			continue
+		}
+
+		rc, err := CloneComponent(source)
+		if err != nil {
+			return nil, err
+		}
+
+		if replaced {
+			rc = &ReplacedComponent{Component: rc}
+		}
+
+		rc.SetAction(action)
+		rc.SetState(STATEWAITING)
+
+		err = nix.addComponent(rc)
+		if err != nil {
+			return nil, err
+		}
+
+		steps[c.GetID()] = rc.GetID()
+		reverts[rc.GetID()] = c.GetID()
+	}
+
+	reverted := func(id string) bool {
+		_, ok := steps[id]
+		return ok
+	}
+
+	for _, rc := range ng.Components {
+		for _, r := range ix.reachable(reverts[rc.GetID()], reverted) {
+			nix.connect(steps[r], rc.GetID())
+		}
+	}
+
+	nix.setStartFinish()
+
+	ng.Changes = ng.Components
+	ng.Components = g.Components
+
+	return ng, nil
+}
+
+// reachable returns all vertices matching the filter that can be reached from a vertex,
+// only following paths through vertices that do not match the filter
+func (ix *index) reachable(id string, filter func(string) bool) []string {
+	var found []string
+
+	neighbours := ix.neighbours
+	visited := map[string]bool{id: true}
+
+	var search func(v string)
+	search = func(v string) {
+		for _, n := range neighbours[v] {
+			if visited[n] || isTerminal(n) {
+				continue
+			}
+			visited[n] = true
+
+			if filter(n) {
+				found = append(found, n)
+				continue
+			}
+
+			search(n)
+		}
+	}
+
+	search(id)
+
+	return found
+}
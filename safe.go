@@ -0,0 +1,202 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "sync"
+
+// SafeGraph wraps a graph, so it can be used from multiple goroutines. All reads share a read lock,
+// while all writes hold the write lock. Components can be changed directly, i.e. by calling SetState,
+// while holding the write lock with Lock and Unlock, such as by setting the executor's Locker
+type SafeGraph struct {
+	mu    sync.RWMutex
+	graph *Graph
+}
+
+// NewSafeGraph returns a new SafeGraph wrapping a graph. The graph should not be used directly afterwards
+func NewSafeGraph(g *Graph) *SafeGraph {
+	return &SafeGraph{graph: g}
+}
+
+// Lock acquires the write lock
+func (s *SafeGraph) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock releases the write lock
+func (s *SafeGraph) Unlock() {
+	s.mu.Unlock()
+}
+
+// RLock acquires a read lock
+func (s *SafeGraph) RLock() {
+	s.mu.RLock()
+}
+
+// RUnlock releases a read lock
+func (s *SafeGraph) RUnlock() {
+	s.mu.RUnlock()
+}
+
+// Read calls a function with the graph while holding a read lock. The graph must not be modified
+func (s *SafeGraph) Read(fn func(g *Graph)) {
+	s.RLock()
+	defer s.RUnlock()
+
+	fn(s.graph)
+}
+
+// Write calls a function with the graph while holding the write lock
+func (s *SafeGraph) Write(fn func(g *Graph)) {
+	s.Lock()
+	defer s.Unlock()
+
+	fn(s.graph)
+}
+
+// Component returns a component given the name matches
+func (s *SafeGraph) Component(component string) Component {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.Component(component)
+}
+
+// ComponentAll returns a component from either changes or components given the name matches
+func (s *SafeGraph) ComponentAll(component string) Component {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.ComponentAll(component)
+}
+
+// HasComponent finds if the specified component exists
+func (s *SafeGraph) HasComponent(componentID string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.HasComponent(componentID)
+}
+
+// GetComponents returns a copy of the graph's components
+func (s *SafeGraph) GetComponents() ComponentGroup {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append(ComponentGroup{}, s.graph.Components...)
+}
+
+// GetChanges returns a copy of the graph's changes
+func (s *SafeGraph) GetChanges() ComponentGroup {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append(ComponentGroup{}, s.graph.Changes...)
+}
+
+// Connected returns true if two components are connected
+func (s *SafeGraph) Connected(source, destination string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.Connected(source, destination)
+}
+
+// Neighbours returns all depencencies of a component
+func (s *SafeGraph) Neighbours(component string) *Neighbours {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.Neighbours(component)
+}
+
+// Origins returns all source components of a component
+func (s *SafeGraph) Origins(component string) *Neighbours {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.Origins(component)
+}
+
+// ToJSON serialises the graph as json
+func (s *SafeGraph) ToJSON() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.graph.ToJSON()
+}
+
+// AddComponent adds a component to the graphs vertices if it does not already exist
+func (s *SafeGraph) AddComponent(component Component) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.AddComponent(component)
+}
+
+// UpdateComponent updates the graph's component
+func (s *SafeGraph) UpdateComponent(component Component) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.graph.UpdateComponent(component)
+}
+
+// DeleteComponent deletes a component from the graph
+func (s *SafeGraph) DeleteComponent(component Component) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.graph.DeleteComponent(component)
+}
+
+// DisconnectComponent removes a component from the graph. It will connect any neighbour/origin components together
+func (s *SafeGraph) DisconnectComponent(name string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.DisconnectComponent(name)
+}
+
+// Connect adds a dependency between two vertices
+func (s *SafeGraph) Connect(source, destination string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.Connect(source, destination)
+}
+
+// ConnectMutually connects two vertices to eachother
+func (s *SafeGraph) ConnectMutually(source, destination string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.ConnectMutually(source, destination)
+}
+
+// ConnectComplex connects two vertices, see Graph.ConnectComplex
+func (s *SafeGraph) ConnectComplex(source, destination string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.ConnectComplex(source, destination)
+}
+
+// ConnectComplexUpdate connects two vertices, see Graph.ConnectComplexUpdate
+func (s *SafeGraph) ConnectComplexUpdate(source, destination string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.graph.ConnectComplexUpdate(source, destination)
+}
+
+// SetState sets the state of a change or component
+func (s *SafeGraph) SetState(component, state string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if c := s.graph.ComponentAll(component); c != nil {
+		c.SetState(state)
+	}
+}
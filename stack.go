@@ -4,27 +4,57 @@
 
 package graph
 
-// NodeStack stores a collection of verticies
+// NodeStack stores a collection of verticies, last in first out
 type NodeStack []Component
 
 // Append a verticies onto the stack
-func (n NodeStack) Append(i []Component) {
-	n = append(n, i...)
+func (n *NodeStack) Append(i []Component) {
+	*n = append(*n, i...)
 }
 
 // Prepend a verticies onto the stack
-func (n NodeStack) Prepend(i []Component) {
-	n = append(i, n...)
+func (n *NodeStack) Prepend(i []Component) {
+	*n = append(append([]Component{}, i...), *n...)
 }
 
-// Pop a component from the stack
-func (n NodeStack) Pop() Component {
-	var x Component
-	x, n = n[len(n)-1], n[:len(n)-1]
+// Pop a component from the stack, returns nil if the stack is empty
+func (n *NodeStack) Pop() Component {
+	if n.Empty() {
+		return nil
+	}
+
+	x := (*n)[len(*n)-1]
+	*n = (*n)[:len(*n)-1]
+
+	return x
+}
+
+// Empty returns true if there are no more verticies left
+func (n *NodeStack) Empty() bool {
+	return len(*n) < 1
+}
+
+// NodeQueue stores a collection of verticies, first in first out
+type NodeQueue []Component
+
+// Append a verticies onto the queue
+func (n *NodeQueue) Append(i []Component) {
+	*n = append(*n, i...)
+}
+
+// Pop a component from the front of the queue, returns nil if the queue is empty
+func (n *NodeQueue) Pop() Component {
+	if n.Empty() {
+		return nil
+	}
+
+	x := (*n)[0]
+	*n = (*n)[1:]
+
 	return x
 }
 
 // Empty returns true if there are no more verticies left
-func (n NodeStack) Empty() bool {
-	return len(n) < 1
+func (n *NodeQueue) Empty() bool {
+	return len(*n) < 1
 }
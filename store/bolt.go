@@ -0,0 +1,248 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package store
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/r3labs/graph"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	graphsBucket = []byte("graphs")
+	timesBucket  = []byte("times")
+)
+
+// BoltStore stores graphs in an embedded bolt database. Every graph is stored in its own
+// bucket, with one key per version, while the time each version was stored is kept in a
+// separate bucket. Writes are serialised by the database's transactions
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens or creates a bolt database at the given path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(graphsBucket)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(timesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Get returns a version of a graph, or its latest version if version is LATEST
+func (s *BoltStore) Get(id string, version int) (*graph.Graph, error) {
+	var data []byte
+
+	if id == "" {
+		return nil, ErrNoID
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(graphsBucket).Bucket([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		var v []byte
+		if version == LATEST {
+			_, v = b.Cursor().Last()
+		} else {
+			v = b.Get(key(version))
+		}
+
+		if v == nil {
+			return ErrNotFound
+		}
+
+		data = append([]byte{}, v...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
+
+// Put stores a new version of a graph, failing with ErrConflict if its latest version is not the expected version
+func (s *BoltStore) Put(g *graph.Graph, expected int) (int, error) {
+	var version int
+
+	if g.ID == "" {
+		return 0, ErrNoID
+	}
+
+	data, err := g.ToJSON()
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(graphsBucket).CreateBucketIfNotExists([]byte(g.ID))
+		if err != nil {
+			return err
+		}
+
+		latest := 0
+		if k, _ := b.Cursor().Last(); k != nil {
+			latest = int(binary.BigEndian.Uint64(k))
+		}
+
+		if latest != expected {
+			return ErrConflict
+		}
+
+		version = latest + 1
+
+		tb, err := tx.Bucket(timesBucket).CreateBucketIfNotExists([]byte(g.ID))
+		if err != nil {
+			return err
+		}
+
+		t, err := time.Now().UTC().MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		err = tb.Put(key(version), t)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key(version), data)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// List returns the ids of all stored graphs
+func (s *BoltStore) List() ([]string, error) {
+	var ids []string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(graphsBucket).ForEach(func(k, v []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+
+	return ids, err
+}
+
+// Delete deletes all versions of a graph
+func (s *BoltStore) Delete(id string) error {
+	if id == "" {
+		return ErrNoID
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(graphsBucket).DeleteBucket([]byte(id))
+		if err == bolt.ErrBucketNotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(timesBucket).DeleteBucket([]byte(id))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// Versions returns all versions of a graph, in ascending order
+func (s *BoltStore) Versions(id string) ([]int, error) {
+	var versions []int
+
+	if id == "" {
+		return nil, ErrNoID
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(graphsBucket).Bucket([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			versions = append(versions, int(binary.BigEndian.Uint64(k)))
+			return nil
+		})
+	})
+
+	return versions, err
+}
+
+// History returns all versions of a graph and when they were stored, in ascending order
+func (s *BoltStore) History(id string) ([]Version, error) {
+	var history []Version
+
+	if id == "" {
+		return nil, ErrNoID
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(graphsBucket).Bucket([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		tb := tx.Bucket(timesBucket).Bucket([]byte(id))
+
+		return b.ForEach(func(k, v []byte) error {
+			version := Version{Number: int(binary.BigEndian.Uint64(k))}
+
+			if tb != nil {
+				if t := tb.Get(k); t != nil {
+					err := version.Time.UnmarshalBinary(t)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			history = append(history, version)
+
+			return nil
+		})
+	})
+
+	return history, err
+}
+
+// key returns the database key of a version, sorting versions in ascending order
+func key(version int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(version))
+	return k
+}
@@ -0,0 +1,267 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package store
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r3labs/graph"
+)
+
+// DirStore stores graphs as json files in a local directory. Every graph is stored in its
+// own directory, named by the hex encoding of its id, with one file per version. Conflicts are detected between processes
+// sharing the directory, as a version's file can only be created once. Each file holds the
+// graph along with the time its version was stored
+type DirStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// dirVersion is the json representation of a stored version of a graph
+type dirVersion struct {
+	Time  time.Time       `json:"time"`
+	Graph json.RawMessage `json:"graph"`
+}
+
+// NewDirStore returns a new store using the given directory, creating it if it does not exist
+func NewDirStore(path string) (*DirStore, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirStore{path: path}, nil
+}
+
+// Get returns a version of a graph, or its latest version if version is LATEST
+func (s *DirStore) Get(id string, version int) (*graph.Graph, error) {
+	if id == "" {
+		return nil, ErrNoID
+	}
+
+	if version == LATEST {
+		versions, err := s.Versions(id)
+		if err != nil {
+			return nil, err
+		}
+		version = versions[len(versions)-1]
+	}
+
+	v, err := s.read(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(v.Graph)
+}
+
+// Put stores a new version of a graph, failing with ErrConflict if its latest version is not the expected version
+func (s *DirStore) Put(g *graph.Graph, expected int) (int, error) {
+	if g.ID == "" {
+		return 0, ErrNoID
+	}
+
+	data, err := g.ToJSON()
+	if err != nil {
+		return 0, err
+	}
+
+	data, err = json.Marshal(dirVersion{Time: time.Now().UTC(), Graph: data})
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest, err := s.latest(g.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if latest != expected {
+		return 0, ErrConflict
+	}
+
+	err = os.MkdirAll(s.dir(g.ID), 0700)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := ioutil.TempFile(s.dir(g.ID), ".tmp-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// linking fails if another process has already stored this version
+	err = os.Link(tmp.Name(), s.file(g.ID, latest+1))
+	if os.IsExist(err) {
+		return 0, ErrConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return latest + 1, nil
+}
+
+// List returns the ids of all stored graphs
+func (s *DirStore) List() ([]string, error) {
+	var ids []string
+
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		id, err := hex.DecodeString(e.Name())
+		if err != nil || len(id) < 1 {
+			continue
+		}
+
+		ids = append(ids, string(id))
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// Delete deletes all versions of a graph
+func (s *DirStore) Delete(id string) error {
+	if id == "" {
+		return ErrNoID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := os.Stat(s.dir(id))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(s.dir(id))
+}
+
+// Versions returns all versions of a graph, in ascending order
+func (s *DirStore) Versions(id string) ([]int, error) {
+	var versions []int
+
+	if id == "" {
+		return nil, ErrNoID
+	}
+
+	entries, err := ioutil.ReadDir(s.dir(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		v, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	if len(versions) < 1 {
+		return nil, ErrNotFound
+	}
+
+	sort.Ints(versions)
+
+	return versions, nil
+}
+
+// History returns all versions of a graph and when they were stored, in ascending order
+func (s *DirStore) History(id string) ([]Version, error) {
+	var history []Version
+
+	versions, err := s.Versions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range versions {
+		v, err := s.read(id, n)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, Version{Number: n, Time: v.Time.UTC()})
+	}
+
+	return history, nil
+}
+
+// latest returns the latest version of a graph, or 0 if the graph has not been stored
+func (s *DirStore) latest(id string) (int, error) {
+	versions, err := s.Versions(id)
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return versions[len(versions)-1], nil
+}
+
+// read loads a stored version of a graph
+func (s *DirStore) read(id string, version int) (*dirVersion, error) {
+	var v dirVersion
+
+	data, err := ioutil.ReadFile(s.file(id, version))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, json.Unmarshal(data, &v)
+}
+
+// dir returns the directory of a graph. Ids are hex encoded, so no id can refer to a path outside of the store
+func (s *DirStore) dir(id string) string {
+	return filepath.Join(s.path, hex.EncodeToString([]byte(id)))
+}
+
+func (s *DirStore) file(id string, version int) string {
+	return filepath.Join(s.dir(id), strconv.Itoa(version)+".json")
+}
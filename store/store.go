@@ -0,0 +1,86 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/r3labs/graph"
+)
+
+// LATEST : gets the latest version of a graph
+const LATEST = 0
+
+var (
+	// ErrNotFound : the graph or version does not exist
+	ErrNotFound = errors.New("Graph not found")
+	// ErrConflict : the graph has been updated since the expected version
+	ErrConflict = errors.New("Graph has been updated by another writer")
+	// ErrNoID : a graph can not be stored or read without an id
+	ErrNoID = errors.New("Graph has no id")
+)
+
+// Store persists graphs by their ID, keeping every version of a graph. Versions start at 1 and increase
+// by one every time a graph is stored. Writers detect conflicts with optimistic concurrency, by providing
+// the version of the graph they last read when storing it
+type Store interface {
+	Get(id string, version int) (*graph.Graph, error) // returns a version of a graph, or its latest version if version is LATEST
+	Put(g *graph.Graph, expected int) (int, error)    // stores a new version of a graph, failing with ErrConflict if its latest version is not the expected version. The expected version of a new graph is 0
+	List() ([]string, error)                          // returns the ids of all stored graphs
+	Delete(id string) error                           // deletes all versions of a graph
+	Versions(id string) ([]int, error)                // returns all versions of a graph, in ascending order
+	History(id string) ([]Version, error)             // returns all versions of a graph and when they were stored, in ascending order
+}
+
+// Version describes a stored version of a graph
+type Version struct {
+	Number int
+	Time   time.Time
+}
+
+// At returns the version of a graph that was the latest at the given time
+func At(s Store, id string, t time.Time) (*graph.Graph, error) {
+	history, err := s.History(id)
+	if err != nil {
+		return nil, err
+	}
+
+	version := 0
+
+	for _, v := range history {
+		if !v.Time.After(t) {
+			version = v.Number
+		}
+	}
+
+	if version == 0 {
+		return nil, ErrNotFound
+	}
+
+	return s.Get(id, version)
+}
+
+// DiffVersions diffs two versions of a graph, returning the changes needed to get from version a to version b
+// along with their changelog
+func DiffVersions(s Store, id string, a, b int) (*graph.Graph, error) {
+	ga, err := s.Get(id, a)
+	if err != nil {
+		return nil, err
+	}
+
+	gb, err := s.Get(id, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return gb.DiffWithChangelog(ga)
+}
+
+// decode loads a stored graph
+func decode(data []byte) (*graph.Graph, error) {
+	g := graph.New()
+	return g, g.UnmarshalJSON(data)
+}
@@ -0,0 +1,317 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package store
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r3labs/graph"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testGraph(id string, components ...string) *graph.Graph {
+	g := graph.New()
+	g.ID = id
+
+	for _, cid := range components {
+		c := graph.MapGenericComponent(map[string]interface{}{
+			"_component_id": cid,
+			"_component":    "instance",
+			"_provider":     "test",
+		})
+		g.AddComponent(c)
+	}
+
+	g.SetStartFinish()
+
+	return g
+}
+
+func testStore(s Store) {
+	Convey("When storing a new graph", func() {
+		v, err := s.Put(testGraph("web/prod", "a"), 0)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+
+		Convey("It should be returned by its id", func() {
+			g, err := s.Get("web/prod", LATEST)
+			So(err, ShouldBeNil)
+			So(g.ID, ShouldEqual, "web/prod")
+			So(g.HasComponent("a"), ShouldBeTrue)
+			So(g.Connected("start", "a"), ShouldBeTrue)
+
+			ids, err := s.List()
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, []string{"web/prod"})
+		})
+
+		Convey("When storing a new version of the graph", func() {
+			v, err := s.Put(testGraph("web/prod", "a", "b"), 1)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 2)
+
+			Convey("It should keep every version", func() {
+				versions, err := s.Versions("web/prod")
+				So(err, ShouldBeNil)
+				So(versions, ShouldResemble, []int{1, 2})
+
+				g, err := s.Get("web/prod", 1)
+				So(err, ShouldBeNil)
+				So(len(g.Components), ShouldEqual, 1)
+
+				g, err = s.Get("web/prod", LATEST)
+				So(err, ShouldBeNil)
+				So(len(g.Components), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When storing a graph based on an outdated version", func() {
+			_, err := s.Put(testGraph("web/prod", "a", "b"), 1)
+			So(err, ShouldBeNil)
+			_, err = s.Put(testGraph("web/prod", "a", "c"), 1)
+			Convey("It should conflict", func() {
+				So(err, ShouldEqual, ErrConflict)
+				versions, _ := s.Versions("web/prod")
+				So(versions, ShouldResemble, []int{1, 2})
+			})
+		})
+
+		Convey("When storing a graph that already exists as a new graph", func() {
+			_, err := s.Put(testGraph("web/prod", "a"), 0)
+			Convey("It should conflict", func() {
+				So(err, ShouldEqual, ErrConflict)
+			})
+		})
+
+		Convey("When deleting the graph", func() {
+			err := s.Delete("web/prod")
+			So(err, ShouldBeNil)
+			Convey("It should delete all versions", func() {
+				_, err := s.Get("web/prod", LATEST)
+				So(err, ShouldEqual, ErrNotFound)
+				_, err = s.Versions("web/prod")
+				So(err, ShouldEqual, ErrNotFound)
+				So(s.Delete("web/prod"), ShouldEqual, ErrNotFound)
+			})
+		})
+	})
+
+	Convey("When storing multiple versions of a graph", func() {
+		before := time.Now().UTC()
+
+		for i, g := range []*graph.Graph{testGraph("env", "a"), testGraph("env", "a", "b"), testGraph("env", "b", "c")} {
+			_, err := s.Put(g, i)
+			So(err, ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		history, err := s.History("env")
+		So(err, ShouldBeNil)
+
+		Convey("It should record when every version was stored", func() {
+			So(len(history), ShouldEqual, 3)
+			for i, v := range history {
+				So(v.Number, ShouldEqual, i+1)
+				So(v.Time, ShouldHappenOnOrBetween, before, time.Now().UTC())
+				if i > 0 {
+					So(v.Time, ShouldHappenOnOrAfter, history[i-1].Time)
+				}
+			}
+		})
+
+		Convey("When getting the graph at a point in time", func() {
+			g, err := At(s, "env", history[1].Time)
+			So(err, ShouldBeNil)
+			Convey("It should return the latest version at that time", func() {
+				So(len(g.Components), ShouldEqual, 2)
+				So(g.HasComponent("b"), ShouldBeTrue)
+				So(g.HasComponent("c"), ShouldBeFalse)
+			})
+
+			g, err = At(s, "env", time.Now())
+			So(err, ShouldBeNil)
+			So(g.HasComponent("c"), ShouldBeTrue)
+
+			_, err = At(s, "env", before.Add(-time.Hour))
+			So(err, ShouldEqual, ErrNotFound)
+		})
+
+		Convey("When diffing two versions", func() {
+			g, err := DiffVersions(s, "env", 1, 3)
+			So(err, ShouldBeNil)
+			Convey("It should return the changes between them", func() {
+				So(len(g.Changes), ShouldEqual, 3)
+				So(g.ComponentAll("a").GetAction(), ShouldEqual, graph.ACTIONDELETE)
+				So(g.ComponentAll("b").GetAction(), ShouldEqual, graph.ACTIONCREATE)
+				So(g.ComponentAll("c").GetAction(), ShouldEqual, graph.ACTIONCREATE)
+				So(len(g.Changelog), ShouldBeGreaterThan, 0)
+			})
+
+			_, err = DiffVersions(s, "env", 1, 4)
+			So(err, ShouldEqual, ErrNotFound)
+		})
+	})
+
+	Convey("When multiple writers update the same graph concurrently", func() {
+		_, err := s.Put(testGraph("db", "a"), 0)
+		So(err, ShouldBeNil)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var conflicts int
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.Put(testGraph("db", "a", "b"), 1)
+				if err == ErrConflict {
+					mu.Lock()
+					conflicts++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		Convey("Only one writer should succeed", func() {
+			So(conflicts, ShouldEqual, 9)
+			versions, _ := s.Versions("db")
+			So(versions, ShouldResemble, []int{1, 2})
+		})
+	})
+
+	Convey("When getting a graph that does not exist", func() {
+		_, err := s.Get("missing", LATEST)
+		Convey("It should not be found", func() {
+			So(err, ShouldEqual, ErrNotFound)
+		})
+	})
+
+	Convey("When storing a graph without an id", func() {
+		_, err := s.Put(graph.New(), 0)
+		Convey("It should error", func() {
+			So(err, ShouldEqual, ErrNoID)
+		})
+	})
+
+	Convey("When reading or deleting a graph without an id", func() {
+		_, err := s.Put(testGraph("a", "a"), 0)
+		So(err, ShouldBeNil)
+		Convey("It should error", func() {
+			_, err := s.Get("", LATEST)
+			So(err, ShouldEqual, ErrNoID)
+			_, err = s.Versions("")
+			So(err, ShouldEqual, ErrNoID)
+			_, err = s.History("")
+			So(err, ShouldEqual, ErrNoID)
+			So(s.Delete(""), ShouldEqual, ErrNoID)
+		})
+		Convey("It should not delete any graphs", func() {
+			_ = s.Delete("")
+			ids, err := s.List()
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, []string{"a"})
+		})
+	})
+
+	Convey("When storing graphs with ids that are paths", func() {
+		ids := []string{".", "..", "../a", "a/../../b", "/"}
+		for i, id := range ids {
+			_, err := s.Put(testGraph(id, string(rune('a'+i))), 0)
+			So(err, ShouldBeNil)
+		}
+		Convey("It should store every graph separately", func() {
+			stored, err := s.List()
+			So(err, ShouldBeNil)
+			So(stored, ShouldResemble, []string{".", "..", "../a", "/", "a/../../b"})
+			for i, id := range ids {
+				g, err := s.Get(id, LATEST)
+				So(err, ShouldBeNil)
+				So(g.ID, ShouldEqual, id)
+				So(g.Components[0].GetID(), ShouldEqual, string(rune('a'+i)))
+			}
+		})
+		Convey("It should only delete the graph", func() {
+			So(s.Delete(".."), ShouldBeNil)
+			So(s.Delete("."), ShouldBeNil)
+			stored, err := s.List()
+			So(err, ShouldBeNil)
+			So(stored, ShouldResemble, []string{"../a", "/", "a/../../b"})
+		})
+	})
+}
+
+func TestDirStore(t *testing.T) {
+	Convey("Given a directory store", t, func() {
+		dir, err := ioutil.TempDir("", "store")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		s, err := NewDirStore(dir)
+		So(err, ShouldBeNil)
+
+		testStore(s)
+
+		Convey("When the files of a stored graph are modified", func() {
+			before := time.Now().UTC()
+			_, err := s.Put(testGraph("env", "a"), 0)
+			So(err, ShouldBeNil)
+
+			modified := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			So(os.Chtimes(filepath.Join(dir, hex.EncodeToString([]byte("env")), "1.json"), modified, modified), ShouldBeNil)
+
+			Convey("It should keep the time each version was stored", func() {
+				history, err := s.History("env")
+				So(err, ShouldBeNil)
+				So(len(history), ShouldEqual, 1)
+				So(history[0].Time, ShouldHappenOnOrBetween, before, time.Now().UTC())
+			})
+		})
+
+		Convey("When storing graphs with ids that are relative paths", func() {
+			for _, id := range []string{"..", "../escaped", "a/b"} {
+				_, err := s.Put(testGraph(id, "a"), 0)
+				So(err, ShouldBeNil)
+			}
+			Convey("It should keep every graph inside the directory", func() {
+				entries, err := ioutil.ReadDir(filepath.Dir(dir))
+				So(err, ShouldBeNil)
+				for _, e := range entries {
+					So(e.Name(), ShouldNotEqual, "escaped")
+				}
+				entries, err = ioutil.ReadDir(dir)
+				So(err, ShouldBeNil)
+				So(len(entries), ShouldEqual, 3)
+			})
+			Convey("It should not delete the directory or its parent", func() {
+				So(s.Delete(".."), ShouldBeNil)
+				_, err := os.Stat(dir)
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestBoltStore(t *testing.T) {
+	Convey("Given a bolt store", t, func() {
+		dir, err := ioutil.TempDir("", "store")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		s, err := NewBoltStore(filepath.Join(dir, "graphs.db"))
+		So(err, ShouldBeNil)
+		defer s.Close()
+
+		testStore(s)
+	})
+}
@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+// Subgraph returns a new graph containing the components and changes matching a filter,
+// along with the edges between them. The start and end vertices are always kept
+func (g *Graph) Subgraph(filter func(Component) bool) *Graph {
+	return g.subgraph(filter, false)
+}
+
+// CollapsedSubgraph returns a new graph containing the components and changes matching a filter.
+// Removed vertices are collapsed, so their origins are connected to their neighbours, in the
+// same way as DisconnectComponent. Paths from start to end through removed vertices are not kept
+func (g *Graph) CollapsedSubgraph(filter func(Component) bool) *Graph {
+	return g.subgraph(filter, true)
+}
+
+func (g *Graph) subgraph(filter func(Component) bool, collapse bool) *Graph {
+	ng := g.emptyCopy()
+
+	kept := make(map[string]bool)
+
+	for _, c := range g.Components {
+		if filter(c) {
+			ng.Components = append(ng.Components, c)
+			kept[c.GetID()] = true
+		}
+	}
+
+	for _, c := range g.Changes {
+		if filter(c) {
+			ng.Changes = append(ng.Changes, c)
+			kept[c.GetID()] = true
+		}
+	}
+
+	for _, e := range g.Edges {
+		if isTerminal(e.Source) {
+			kept[e.Source] = true
+		}
+		if isTerminal(e.Destination) {
+			kept[e.Destination] = true
+		}
+	}
+
+	nix := ng.index()
+
+	for _, e := range g.Edges {
+		if kept[e.Source] && kept[e.Destination] && !nix.connected[edgeKey(e.Source, e.Destination)] {
+			nix.addEdge(e)
+		}
+	}
+
+	if !collapse {
+		return ng
+	}
+
+	neighbours := g.index().neighbours
+
+	for _, e := range g.Edges {
+		if !kept[e.Source] || kept[e.Destination] {
+			continue
+		}
+
+		visited := map[string]bool{e.Source: true, e.Destination: true}
+		queue := []string{e.Destination}
+
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+
+			for _, n := range neighbours[v] {
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+
+				if kept[n] {
+					// a path from start to end through removed vertices is not kept
+					if !isTerminal(e.Source) || !isTerminal(n) {
+						nix.connect(e.Source, n)
+					}
+					continue
+				}
+
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return ng
+}
@@ -0,0 +1,111 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"errors"
+	"strings"
+)
+
+// Target returns a new graph from a diffed graph, containing only the given changes and the changes they require.
+// All changes a targeted change depends on are included, as well as the deletion of any components depending on
+// a targeted deletion and both steps of a targeted replacement. Edges between the included changes are kept and
+// start and end are reattached. All changes that are not included are returned separately
+func (g *Graph) Target(ids ...string) (*Graph, ComponentGroup, error) {
+	var excluded ComponentGroup
+
+	ix := g.index()
+	changes := ix.changesByID
+	origins := ix.origins
+	selected := make(map[string]bool)
+
+	queue := append([]string{}, ids...)
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		if selected[id] {
+			continue
+		}
+
+		c, ok := changes[id]
+		if !ok {
+			return nil, nil, errors.New("Could not find change: " + id)
+		}
+
+		selected[id] = true
+
+		for _, o := range origins[id] {
+			if _, ok := changes[o]; ok {
+				queue = append(queue, o)
+			}
+		}
+
+		switch c.GetAction() {
+		case ACTIONREPLACE:
+			if _, ok := changes[id+REPLACEDSUFFIX]; ok {
+				queue = append(queue, id+REPLACEDSUFFIX)
+			}
+		case ACTIONDELETE:
+			if rc, ok := c.(*ReplacedComponent); ok {
+				queue = append(queue, rc.Component.GetID())
+			}
+			queue = append(queue, g.deletedDependents(id)...)
+		}
+	}
+
+	ng := g.emptyCopy()
+	nix := ng.index()
+
+	for _, c := range g.Changes {
+		if !selected[c.GetID()] {
+			excluded = append(excluded, c)
+			continue
+		}
+
+		nix.addComponent(c)
+	}
+
+	for _, e := range g.Edges {
+		if selected[e.Source] && selected[e.Destination] && !nix.connected[edgeKey(e.Source, e.Destination)] {
+			nix.addEdge(e)
+		}
+	}
+
+	nix.setStartFinish()
+
+	for _, change := range g.Changelog {
+		if len(change.Path) > 0 && selected[change.Path[0]] {
+			ng.Changelog = append(ng.Changelog, change)
+		}
+	}
+
+	ng.Changes = ng.Components
+	ng.Components = g.Components
+
+	return ng, excluded, nil
+}
+
+// deletedDependents returns all deleted changes that depend on a component
+func (g *Graph) deletedDependents(id string) []string {
+	var dependents []string
+
+	id = strings.TrimSuffix(id, REPLACEDSUFFIX)
+
+	for _, c := range g.Changes {
+		if c.GetAction() != ACTIONDELETE {
+			continue
+		}
+
+		for _, dep := range c.Dependencies() {
+			if dep == id {
+				dependents = append(dependents, c.GetID())
+			}
+		}
+	}
+
+	return dependents
+}
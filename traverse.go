@@ -0,0 +1,88 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+// Visitor is called for every component visited while traversing the graph.
+// Returning false stops the traversal
+type Visitor func(c Component) bool
+
+// nodes stores the verticies that are still to be visited
+type nodes interface {
+	Append([]Component)
+	Pop() Component
+	Empty() bool
+}
+
+// DFS visits every component reachable from a vertex depth first, following edges from source
+// to destination. The start and end vertices can be traversed from, but are not visited.
+// Every component is visited once, including the component traversed from
+func (g *Graph) DFS(from string, visit Visitor) {
+	ix := g.index()
+	ix.traverse(&NodeStack{}, ix.neighbours, from, visit)
+}
+
+// BFS visits every component reachable from a vertex breadth first, following edges from source to destination
+func (g *Graph) BFS(from string, visit Visitor) {
+	ix := g.index()
+	ix.traverse(&NodeQueue{}, ix.neighbours, from, visit)
+}
+
+// ReverseDFS visits every component a vertex can be reached from depth first, following edges from destination to source
+func (g *Graph) ReverseDFS(from string, visit Visitor) {
+	ix := g.index()
+	ix.traverse(&NodeStack{}, ix.origins, from, visit)
+}
+
+// ReverseBFS visits every component a vertex can be reached from breadth first, following edges from destination to source
+func (g *Graph) ReverseBFS(from string, visit Visitor) {
+	ix := g.index()
+	ix.traverse(&NodeQueue{}, ix.origins, from, visit)
+}
+
+func (ix *index) traverse(n nodes, adjacency map[string][]string, from string, visit Visitor) {
+	_, stack := n.(*NodeStack)
+
+	visited := make(map[string]bool)
+
+	adjacent := func(id string) []Component {
+		var cs []Component
+
+		for _, a := range adjacency[id] {
+			if c := ix.componentAll(a); c != nil && !visited[a] {
+				cs = append(cs, c)
+			}
+		}
+
+		// components are pushed in reverse, so they are popped from the stack in order
+		if stack {
+			for i, j := 0, len(cs)-1; i < j; i, j = i+1, j-1 {
+				cs[i], cs[j] = cs[j], cs[i]
+			}
+		}
+
+		return cs
+	}
+
+	if c := ix.componentAll(from); c != nil {
+		n.Append([]Component{c})
+	} else {
+		n.Append(adjacent(from))
+	}
+
+	for !n.Empty() {
+		c := n.Pop()
+
+		if visited[c.GetID()] {
+			continue
+		}
+		visited[c.GetID()] = true
+
+		if !visit(c) {
+			return
+		}
+
+		n.Append(adjacent(c.GetID()))
+	}
+}
@@ -0,0 +1,82 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import "errors"
+
+var actions = map[string]bool{
+	ACTIONCREATE:  true,
+	ACTIONUPDATE:  true,
+	ACTIONDELETE:  true,
+	ACTIONFIND:    true,
+	ACTIONGET:     true,
+	ACTIONNONE:    true,
+	ACTIONREPLACE: true,
+}
+
+// Validate checks the structure of the graph. Every component is validated and checked for a known action
+// and resolvable dependencies, while the graph is checked for duplicate components, edges referencing
+// components that do not exist and cycles. One cycle is reported for every strongly connected component
+// that contains a cycle. All problems are returned as a MultiError of ComponentError's
+func (g *Graph) Validate() error {
+	var errs []error
+
+	ix := g.index()
+
+	report := func(id string, err error) {
+		errs = append(errs, &ComponentError{ComponentID: id, Err: err})
+	}
+
+	for _, components := range [][]Component{g.Components, g.Changes} {
+		seen := make(map[string]bool)
+
+		for _, c := range components {
+			if seen[c.GetID()] {
+				report(c.GetID(), errors.New("Duplicate component id"))
+			}
+			seen[c.GetID()] = true
+		}
+	}
+
+	for _, c := range g.vertices() {
+		err := c.Validate()
+		if err != nil {
+			report(c.GetID(), err)
+		}
+
+		if c.GetAction() != "" && !actions[c.GetAction()] {
+			report(c.GetID(), errors.New("Unknown action: "+c.GetAction()))
+		}
+
+		for _, dep := range c.Dependencies() {
+			if ix.componentAll(dep) == nil {
+				report(c.GetID(), errors.New("Depends on a component that does not exist: "+dep))
+			}
+		}
+	}
+
+	reported := make(map[string]bool)
+
+	for _, e := range g.Edges {
+		for _, id := range []string{e.Source, e.Destination} {
+			if isTerminal(id) || reported[id] || ix.componentAll(id) != nil {
+				continue
+			}
+			reported[id] = true
+
+			report(id, errors.New("Edge references a component that does not exist"))
+		}
+	}
+
+	for _, cycle := range g.cyclicComponents() {
+		report(cycle[0], &CycleError{Cycle: cycle})
+	}
+
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+
+	return nil
+}